@@ -60,17 +60,7 @@ func (arb Arbiter) Bodies() (Body, Body) {
 // ContactPoints returns a contact set from an arbiter.
 func (a Arbiter) ContactPoints() []ContactPoint {
 	set := C.cpArbiterGetContactPointSet(a.c())
-	c := make([]ContactPoint, int(set.count))
-
-	for i := range c {
-		c[i] = ContactPoint{
-			cpVect(set.points[i].point),
-			cpVect(set.points[i].normal),
-			float64(set.points[i].dist),
-		}
-	}
-
-	return c
+	return cpContactPoints(&set)
 }
 
 // SetContactPoints replaces the contact point set for an arbiter.
@@ -193,7 +183,29 @@ func (a Arbiter) c() *C.cpArbiter {
 	return (*C.cpArbiter)(unsafe.Pointer(a))
 }
 
+// swap reverses the order in which the arbiter reports its shapes and bodies
+// (and so the direction of its normals and impulses).
+func (a Arbiter) swap() {
+	c := a.c()
+	c.swappedColl_private = boolToC(!cpBool(c.swappedColl_private))
+}
+
 // cpArbiter converts C.cpArbiter pointer to Arbiter.
 func cpArbiter(a *C.cpArbiter) Arbiter {
 	return Arbiter(unsafe.Pointer(a))
 }
+
+// cpContactPoints converts C.cpContactPointSet to a slice of contact points.
+func cpContactPoints(set *C.cpContactPointSet) []ContactPoint {
+	c := make([]ContactPoint, int(set.count))
+
+	for i := range c {
+		c[i] = ContactPoint{
+			cpVect(set.points[i].point),
+			cpVect(set.points[i].normal),
+			float64(set.points[i].dist),
+		}
+	}
+
+	return c
+}
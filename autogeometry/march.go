@@ -0,0 +1,287 @@
+/*
+Package autogeometry generates collision geometry from images: the contours of
+the pixels above a threshold are traced by marching squares, simplified and
+turned into segment or convex polygon shapes.
+
+	shapes, err := autogeometry.Shapes(space.StaticBody(), img, img.Bounds(), autogeometry.Options{
+		Threshold: 0.5,
+		Tolerance: 1.0,
+	})
+
+Terrain keeps the shapes of an image in a space up to date when its pixels change.
+*/
+package autogeometry
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Frame maps pixel coordinates to world coordinates. The top left corner of the
+// pixel at (0, 0) is at Origin, every pixel is Scale world units wide and,
+// unlike in the image, the y axis points up. A zero Scale means 1.
+type Frame struct {
+	Origin chipmunk.Vect
+	Scale  float64
+}
+
+// Options configure how the geometry is generated.
+type Options struct {
+	// Sampler converts a pixel color to a value, nil means Alpha.
+	Sampler Sampler
+	// Threshold is the value pixels must exceed to be solid.
+	Threshold float64
+	// Tolerance is the maximum distance in world units the simplified contours
+	// may deviate from the traced ones. Zero disables simplification.
+	Tolerance float64
+	// Frame maps pixel coordinates to world coordinates.
+	Frame Frame
+	// Output selects the kind of shapes generated.
+	Output Output
+	// Radius is the radius of segment shapes.
+	Radius float64
+	// TileSize is the size in pixels of the tiles a Terrain is regenerated by.
+	// Zero means 64.
+	TileSize int
+}
+
+// Output is a kind of generated shapes.
+type Output int
+
+// Sampler converts a pixel color to a value, usually in range [0;1].
+type Sampler func(c color.Color) float64
+
+// edgeKey identifies an edge between two neighbor samples, the one at x, y and
+// the one either to the right or below it.
+type edgeKey struct {
+	x, y     int
+	vertical bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	// Segments generates chains of segment shapes along the contours.
+	Segments Output = iota
+	// Polys generates convex polygon shapes filling the contours.
+	Polys
+)
+
+// defaultTileSize is the default size in pixels of terrain tiles.
+const defaultTileSize = 64
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Alpha is a sampler returning the alpha of a color.
+func Alpha(c color.Color) float64 {
+	_, _, _, a := c.RGBA()
+	return float64(a) / 0xffff
+}
+
+// Luminance is a sampler returning the relative luminance of a color.
+func Luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+}
+
+// March traces the contours of the solid pixels of the rectangle of an image.
+// The pixels outside the rectangle are empty, so every contour is a closed
+// polyline. The outlines are wound counter-clockwise and the holes clockwise,
+// in world coordinates. The contours are simplified if Tolerance is set.
+func March(img image.Image, rect image.Rectangle, opts Options) []Polyline {
+	return march(img, rect, false, opts)
+}
+
+// Point converts pixel coordinates to world coordinates.
+func (f Frame) Point(x, y float64) chipmunk.Vect {
+	scale := f.Scale
+	if 0.0 == scale {
+		scale = 1.0
+	}
+	return chipmunk.VectNew(f.Origin.X+x*scale, f.Origin.Y-y*scale)
+}
+
+// march traces the contours of the solid pixels of the rectangle of an image.
+// If open is false, the pixels outside the rectangle are empty. Otherwise they
+// are sampled, but only the cells between pixel centers the rectangle owns are
+// traced, so the contours crossing its border are open polylines meeting the
+// ones of the neighbor rectangles. A cell is owned by the rectangle containing
+// its pixel of the greatest coordinates, or the nearest one at the image border.
+func march(img image.Image, rect image.Rectangle, open bool, opts Options) []Polyline {
+	bounds := img.Bounds()
+	rect = rect.Intersect(bounds)
+	if rect.Empty() {
+		return nil
+	}
+
+	sample := opts.Sampler
+	if nil == sample {
+		sample = Alpha
+	}
+
+	// samples at the pixel centers with a border, of empty ones unless open
+	sampled := rect
+	if open {
+		sampled = rect.Inset(-1).Intersect(bounds)
+	}
+	w, h := rect.Dx()+2, rect.Dy()+2
+	values := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if p := image.Pt(rect.Min.X+x-1, rect.Min.Y+y-1); p.In(sampled) {
+				values[y*w+x] = sample(img.At(p.X, p.Y))
+			}
+		}
+	}
+
+	// the cells traced, by their sample of the lowest coordinates
+	cells := image.Rect(0, 0, w-1, h-1)
+	if open && rect.Max.X < bounds.Max.X {
+		cells.Max.X--
+	}
+	if open && rect.Max.Y < bounds.Max.Y {
+		cells.Max.Y--
+	}
+
+	value := func(x, y int) float64 {
+		return values[y*w+x]
+	}
+
+	// the crossing point is interpolated from the first sample of an edge in
+	// pixel coordinates, so that neighbor rectangles get exactly the same one
+	point := func(e edgeKey) chipmunk.Vect {
+		dx, dy := 1, 0
+		if e.vertical {
+			dx, dy = 0, 1
+		}
+
+		a, b := value(e.x, e.y), value(e.x+dx, e.y+dy)
+		t := (opts.Threshold - a) / (b - a)
+		return opts.Frame.Point(
+			float64(rect.Min.X+e.x-1)+0.5+t*float64(dx),
+			float64(rect.Min.Y+e.y-1)+0.5+t*float64(dy))
+	}
+
+	next := make(map[edgeKey]edgeKey)
+	var starts []edgeKey
+
+	type crossing struct {
+		key   edgeKey
+		enter bool
+	}
+
+	for y := cells.Min.Y; y < cells.Max.Y; y++ {
+		for x := cells.Min.X; x < cells.Max.X; x++ {
+			// corners in cyclic order and the edges following each of them
+			cx := [4]int{x, x + 1, x + 1, x}
+			cy := [4]int{y, y, y + 1, y + 1}
+			edges := [4]edgeKey{{x, y, false}, {x + 1, y, true}, {x, y + 1, false}, {x, y, true}}
+
+			var v [4]float64
+			for k := range v {
+				v[k] = value(cx[k], cy[k])
+			}
+
+			var cs []crossing
+			for k := range v {
+				l := (k + 1) % 4
+				a, b := v[k] > opts.Threshold, v[l] > opts.Threshold
+				if a != b {
+					cs = append(cs, crossing{edges[k], b})
+				}
+			}
+
+			// the solid side is always on the left in world coordinates;
+			// in a saddle the solid corners are connected if the center is solid
+			connected := (v[0]+v[1]+v[2]+v[3])/4.0 > opts.Threshold
+			for i, c := range cs {
+				if !c.enter {
+					continue
+				}
+
+				to := cs[(i+1)%len(cs)].key
+				if 4 == len(cs) && connected {
+					to = cs[(i+3)%4].key
+				}
+				next[c.key] = to
+				starts = append(starts, c.key)
+			}
+		}
+	}
+
+	// open contours are traced from their first edge, before the closed ones
+	entered := make(map[edgeKey]bool, len(next))
+	for _, to := range next {
+		entered[to] = true
+	}
+	sort.SliceStable(starts, func(i, j int) bool {
+		return !entered[starts[i]] && entered[starts[j]]
+	})
+
+	var lines []Polyline
+	for _, start := range starts {
+		if _, ok := next[start]; !ok {
+			continue
+		}
+
+		var line Polyline
+		for key := start; ; {
+			line = append(line, point(key))
+			to, ok := next[key]
+			if !ok {
+				break
+			}
+			delete(next, key)
+			key = to
+			if key == start {
+				line = append(line, line[0])
+				break
+			}
+		}
+
+		if opts.Tolerance > 0.0 {
+			line = line.Simplify(opts.Tolerance)
+		}
+		if len(line) >= 4 || (len(line) >= 2 && !line.IsClosed()) {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// tileSize returns the size of terrain tiles.
+func (o Options) tileSize() int {
+	if o.TileSize > 0 {
+		return o.TileSize
+	}
+	return defaultTileSize
+}
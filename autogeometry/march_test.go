@@ -0,0 +1,88 @@
+package autogeometry
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/ianremmler/chipmunk"
+)
+
+// ringImage returns a 10x10 image with a 6x6 opaque square with a 2x2 hole.
+func ringImage() *image.Alpha {
+	img := image.NewAlpha(image.Rect(0, 0, 10, 10))
+	for y := 2; y < 8; y++ {
+		for x := 2; x < 8; x++ {
+			if x < 4 || x > 5 || y < 4 || y > 5 {
+				img.SetAlpha(x, y, color.Alpha{255})
+			}
+		}
+	}
+	return img
+}
+
+func Test_March(t *testing.T) {
+	lines := March(ringImage(), image.Rect(0, 0, 10, 10), Options{Threshold: 0.5, Tolerance: 0.01})
+	assert.Equal(t, 2, len(lines))
+
+	outline, hole := lines[0], lines[1]
+	assert.T(t, outline.IsClosed())
+	assert.T(t, hole.IsClosed())
+
+	// the corners are cut by the diagonal of half a pixel
+	assert.Equal(t, 9, len(outline))
+	assert.Equal(t, 36.0-0.5, outline.Area())
+	assert.Equal(t, -(4.0 - 0.5), hole.Area())
+}
+
+func Test_MarchRect(t *testing.T) {
+	// the contours are closed along the rectangle
+	lines := March(ringImage(), image.Rect(0, 0, 5, 10), Options{Threshold: 0.5, Tolerance: 0.01})
+	assert.Equal(t, 1, len(lines))
+	assert.T(t, lines[0].Area() > 0.0)
+
+	assert.Equal(t, 0, len(March(ringImage(), image.Rect(20, 20, 30, 30), Options{})))
+}
+
+func Test_MarchFrame(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 3, 3))
+	img.SetGray(1, 1, color.Gray{255})
+
+	opts := Options{
+		Sampler:   Luminance,
+		Threshold: 0.5,
+		Frame:     Frame{Origin: chipmunk.VectNew(10.0, 10.0), Scale: 2.0},
+	}
+	lines := March(img, img.Bounds(), opts)
+	assert.Equal(t, 1, len(lines))
+	assert.Equal(t, 2.0, lines[0].Area())
+
+	bb := chipmunk.BBNewForCircle(chipmunk.VectNew(13.0, 7.0), 1.0)
+	for _, p := range lines[0] {
+		assert.T(t, bb.ContainsVect(p), p)
+	}
+}
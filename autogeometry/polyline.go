@@ -0,0 +1,135 @@
+package autogeometry
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Polyline is a sequence of connected points. It's closed if its last point is
+// the same as its first one.
+type Polyline []chipmunk.Vect
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Area returns the signed area of a closed polyline, positive for a
+// counter-clockwise winding.
+func (l Polyline) Area() float64 {
+	area := 0.0
+	for i := 0; i+1 < len(l); i++ {
+		area += l[i].X*l[i+1].Y - l[i].Y*l[i+1].X
+	}
+	return area / 2.0
+}
+
+// IsClosed returns true if the polyline is closed.
+func (l Polyline) IsClosed() bool {
+	return len(l) > 1 && l[0] == l[len(l)-1]
+}
+
+// Simplify returns the polyline simplified by the Douglas-Peucker algorithm,
+// the points removed are at most tolerance away from the result.
+// A closed polyline stays closed.
+func (l Polyline) Simplify(tolerance float64) Polyline {
+	if len(l) < 3 {
+		return l
+	}
+
+	if !l.IsClosed() {
+		return simplify(l, tolerance)
+	}
+
+	// a closed polyline is split at the point farthest from its start
+	far, dist := 0, 0.0
+	for i, p := range l {
+		if d := p.Dist(l[0]); d > dist {
+			far, dist = i, d
+		}
+	}
+
+	a, b := simplify(l[:far+1], tolerance), simplify(l[far:], tolerance)
+	return append(a, b[1:]...)
+}
+
+// contains returns true if a point is inside a closed polyline.
+func (l Polyline) contains(p chipmunk.Vect) bool {
+	in := false
+	for i := 0; i+1 < len(l); i++ {
+		a, b := l[i], l[i+1]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			in = !in
+		}
+	}
+	return in
+}
+
+// segmentDist returns the distance from a point to the segment a-b.
+func segmentDist(p, a, b chipmunk.Vect) float64 {
+	ab := b.Sub(a)
+	l := ab.Dot(ab)
+	if 0.0 == l {
+		return p.Dist(a)
+	}
+
+	t := p.Sub(a).Dot(ab) / l
+	if t < 0.0 {
+		t = 0.0
+	} else if t > 1.0 {
+		t = 1.0
+	}
+	return p.Dist(a.Add(ab.Mul(t)))
+}
+
+// simplify simplifies an open polyline by the Douglas-Peucker algorithm.
+func simplify(l Polyline, tolerance float64) Polyline {
+	keep := make([]bool, len(l))
+	keep[0], keep[len(l)-1] = true, true
+
+	var split func(first, last int)
+	split = func(first, last int) {
+		far, dist := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			if d := segmentDist(l[i], l[first], l[last]); d > dist {
+				far, dist = i, d
+			}
+		}
+
+		if far >= 0 {
+			keep[far] = true
+			split(first, far)
+			split(far, last)
+		}
+	}
+	split(0, len(l)-1)
+
+	var result Polyline
+	for i, p := range l {
+		if keep[i] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
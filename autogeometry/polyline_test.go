@@ -0,0 +1,45 @@
+package autogeometry
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/ianremmler/chipmunk"
+)
+
+func Test_PolylineSimplify(t *testing.T) {
+	v := chipmunk.VectNew
+
+	open := Polyline{v(0, 0), v(1, 0.05), v(2, 0), v(3, 1), v(4, 2)}
+	assert.Equal(t, Polyline{v(0, 0), v(2, 0), v(4, 2)}, open.Simplify(0.1))
+	assert.Equal(t, open, open.Simplify(0.01))
+
+	closed := Polyline{v(0, 0), v(1, 0), v(2, 0), v(2, 1), v(2, 2), v(0, 2), v(0, 1), v(0, 0)}
+	simple := closed.Simplify(0.1)
+	assert.Equal(t, Polyline{v(0, 0), v(2, 0), v(2, 2), v(0, 2), v(0, 0)}, simple)
+	assert.T(t, simple.IsClosed())
+	assert.Equal(t, 4.0, simple.Area())
+}
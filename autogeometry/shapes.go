@@ -0,0 +1,124 @@
+package autogeometry
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"image"
+	"sort"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// PolyShapes fills closed polylines, as returned by March, with convex polygon
+// shapes attached to a body. Clockwise polylines are holes in the smallest
+// counter-clockwise one containing them.
+func PolyShapes(body chipmunk.Body, lines []Polyline) ([]chipmunk.PolyShape, error) {
+	var outlines []Polyline
+	for _, l := range lines {
+		if l.IsClosed() && l.Area() > 0.0 {
+			outlines = append(outlines, l)
+		}
+	}
+
+	// smallest first, so a hole goes to the innermost outline containing it
+	sort.SliceStable(outlines, func(i, j int) bool {
+		return outlines[i].Area() < outlines[j].Area()
+	})
+
+	holes := make([][][]chipmunk.Vect, len(outlines))
+	for _, l := range lines {
+		if !l.IsClosed() || l.Area() >= 0.0 {
+			continue
+		}
+
+		for i, o := range outlines {
+			if o.contains(l[0]) {
+				holes[i] = append(holes[i], l[:len(l)-1])
+				break
+			}
+		}
+	}
+
+	var shapes []chipmunk.PolyShape
+	for i, o := range outlines {
+		pieces, err := chipmunk.ConvexDecomposition(o[:len(o)-1], holes[i])
+		if err != nil {
+			for _, sh := range shapes {
+				sh.Free()
+			}
+			return nil, err
+		}
+
+		for _, p := range pieces {
+			shapes = append(shapes, chipmunk.PolyShapeNew(body, p, chipmunk.Origin()))
+		}
+	}
+
+	return shapes, nil
+}
+
+// SegmentShapes creates chains of segment shapes of a radius attached to a body
+// along polylines (see chipmunk.SegmentChainNew).
+func SegmentShapes(body chipmunk.Body, lines []Polyline, radius float64) []chipmunk.SegmentShape {
+	var shapes []chipmunk.SegmentShape
+
+	for _, l := range lines {
+		var chain chipmunk.SegmentChain
+		if l.IsClosed() {
+			chain = chipmunk.SegmentChainNew(body, l[:len(l)-1], radius, true)
+		} else {
+			chain = chipmunk.SegmentChainNew(body, l, radius, false)
+		}
+		shapes = append(shapes, chain.Shapes...)
+	}
+
+	return shapes
+}
+
+// Shapes traces the contours of the solid pixels of the rectangle of an image
+// (see March) and creates the shapes selected by Output attached to a body.
+func Shapes(body chipmunk.Body, img image.Image, rect image.Rectangle, opts Options) ([]chipmunk.Shape, error) {
+	lines := March(img, rect, opts)
+	var shapes []chipmunk.Shape
+
+	switch opts.Output {
+	case Polys:
+		polys, err := PolyShapes(body, lines)
+		if err != nil {
+			return nil, err
+		}
+		for _, sh := range polys {
+			shapes = append(shapes, sh)
+		}
+
+	default:
+		for _, sh := range SegmentShapes(body, lines, opts.Radius) {
+			shapes = append(shapes, sh)
+		}
+	}
+
+	return shapes, nil
+}
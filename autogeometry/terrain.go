@@ -0,0 +1,171 @@
+package autogeometry
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Terrain keeps the shapes generated from an image in a space. The image is split
+// in tiles, each with its own shapes, so that only the tiles where pixels change
+// are regenerated. Segment chains cross the borders of tiles and meet there,
+// polygons are split along them.
+type Terrain struct {
+	space chipmunk.Space
+	body  chipmunk.Body
+	img   image.Image
+	opts  Options
+	tiles map[image.Point][]chipmunk.Shape
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewTerrain generates the shapes of an image, attaches them to a body, usually
+// the static body of the space, and adds them to the space.
+func NewTerrain(space chipmunk.Space, body chipmunk.Body, img image.Image, opts Options) (*Terrain, error) {
+	t := &Terrain{
+		space: space,
+		body:  body,
+		img:   img,
+		opts:  opts,
+		tiles: make(map[image.Point][]chipmunk.Shape),
+	}
+
+	if err := t.Update(img.Bounds()); err != nil {
+		t.Free()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Free removes the shapes of the terrain from the space and frees them.
+func (t *Terrain) Free() {
+	for key, shapes := range t.tiles {
+		t.freeShapes(shapes)
+		delete(t.tiles, key)
+	}
+}
+
+// Shapes returns the shapes of the terrain.
+func (t *Terrain) Shapes() []chipmunk.Shape {
+	keys := make([]image.Point, 0, len(t.tiles))
+	for key := range t.tiles {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Y < keys[j].Y || (keys[i].Y == keys[j].Y && keys[i].X < keys[j].X)
+	})
+
+	var shapes []chipmunk.Shape
+	for _, key := range keys {
+		shapes = append(shapes, t.tiles[key]...)
+	}
+	return shapes
+}
+
+// Update regenerates the shapes of the tiles overlapping a rectangle of the
+// image which pixels have changed. It must not be called while the space is locked,
+// the shapes being freed immediately. If the shapes of a tile can't be generated,
+// the terrain is left unchanged.
+func (t *Terrain) Update(dirty image.Rectangle) error {
+	if t.space.IsLocked() {
+		return fmt.Errorf("autogeometry: Terrain.Update: %w", chipmunk.ErrSpaceLocked)
+	}
+
+	if Polys != t.opts.Output {
+		// a pixel is in cells of the tiles after it too (see march)
+		dirty.Max = dirty.Max.Add(image.Pt(1, 1))
+	}
+
+	bounds := t.img.Bounds()
+	dirty = dirty.Intersect(bounds)
+	if dirty.Empty() {
+		return nil
+	}
+
+	size := t.opts.tileSize()
+	min := dirty.Min.Sub(bounds.Min).Div(size)
+	max := dirty.Max.Sub(bounds.Min).Add(image.Pt(size-1, size-1)).Div(size)
+
+	// the shapes of all the tiles are generated before any is replaced
+	tiles := make(map[image.Point][]chipmunk.Shape)
+	for y := min.Y; y < max.Y; y++ {
+		for x := min.X; x < max.X; x++ {
+			tile := image.Rect(x*size, y*size, (x+1)*size, (y+1)*size).Add(bounds.Min).Intersect(bounds)
+			shapes, err := t.tileShapes(tile)
+			if err != nil {
+				for _, shapes := range tiles {
+					for _, sh := range shapes {
+						sh.Free()
+					}
+				}
+				return err
+			}
+			tiles[image.Pt(x, y)] = shapes
+		}
+	}
+
+	for key, shapes := range tiles {
+		t.freeShapes(t.tiles[key])
+		delete(t.tiles, key)
+
+		for _, sh := range shapes {
+			t.space.AddShape(sh)
+		}
+		if len(shapes) > 0 {
+			t.tiles[key] = shapes
+		}
+	}
+
+	return nil
+}
+
+// freeShapes removes shapes from the space and frees them.
+func (t *Terrain) freeShapes(shapes []chipmunk.Shape) {
+	for _, sh := range shapes {
+		t.space.RemoveShape(sh)
+		sh.Free()
+	}
+}
+
+// tileShapes generates the shapes of a tile. Its segment chains are open at
+// the borders of the tile, where they meet the ones of the neighbor tiles.
+func (t *Terrain) tileShapes(tile image.Rectangle) ([]chipmunk.Shape, error) {
+	if Polys == t.opts.Output {
+		return Shapes(t.body, t.img, tile, t.opts)
+	}
+
+	var shapes []chipmunk.Shape
+	for _, sh := range SegmentShapes(t.body, march(t.img, tile, true, t.opts), t.opts.Radius) {
+		shapes = append(shapes, sh)
+	}
+	return shapes, nil
+}
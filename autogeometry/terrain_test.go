@@ -0,0 +1,105 @@
+package autogeometry
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/ianremmler/chipmunk"
+)
+
+// contourLength returns the total length of polylines.
+func contourLength(lines []Polyline) float64 {
+	length := 0.0
+	for _, l := range lines {
+		for i := 0; i+1 < len(l); i++ {
+			length += l[i].Dist(l[i+1])
+		}
+	}
+	return length
+}
+
+// segmentsLength returns the total length of segment shapes.
+func segmentsLength(shapes []chipmunk.Shape) float64 {
+	length := 0.0
+	for _, sh := range shapes {
+		seg := sh.(chipmunk.SegmentShape)
+		length += seg.A().Dist(seg.B())
+	}
+	return length
+}
+
+func Test_Terrain(t *testing.T) {
+	s := chipmunk.SpaceNew()
+	defer s.Free()
+
+	img := ringImage()
+	opts := Options{Threshold: 0.5, Tolerance: 0.01, TileSize: 5}
+	terrain, err := NewTerrain(s, s.StaticBody(), img, opts)
+	assert.Equal(t, nil, err)
+
+	// the chains of the tiles meet at their borders without walls along them
+	before := terrain.Shapes()
+	assert.Equal(t, 4+5+5+6, len(before))
+	for _, sh := range before {
+		assert.T(t, s.Contains(sh.(chipmunk.SpaceObject)))
+	}
+	length := contourLength(March(img, img.Bounds(), opts))
+	assert.T(t, math.Abs(length-segmentsLength(before)) < 1e-9)
+
+	// only the shapes of the top left tile are regenerated
+	img.SetAlpha(1, 2, color.Alpha{255})
+	assert.Equal(t, nil, terrain.Update(image.Rect(1, 2, 2, 3)))
+
+	after := terrain.Shapes()
+	assert.Equal(t, 5+5+5+6, len(after))
+	assert.Equal(t, before[4:], after[5:])
+	length = contourLength(March(img, img.Bounds(), opts))
+	assert.T(t, math.Abs(length-segmentsLength(after)) < 1e-9)
+
+	terrain.Free()
+	assert.Equal(t, 0, len(terrain.Shapes()))
+}
+
+func Test_TerrainPolys(t *testing.T) {
+	s := chipmunk.SpaceNew()
+	defer s.Free()
+
+	opts := Options{Threshold: 0.5, Tolerance: 0.01, Output: Polys}
+	terrain, err := NewTerrain(s, s.StaticBody(), ringImage(), opts)
+	assert.Equal(t, nil, err)
+	defer terrain.Free()
+
+	area := 0.0
+	for _, sh := range terrain.Shapes() {
+		p := sh.(chipmunk.PolyShape)
+		assert.T(t, chipmunk.PolyValidate(p.VertsLocal()))
+		area += chipmunk.AreaForPoly(p.VertsLocal())
+	}
+	assert.T(t, area > 31.99 && area < 32.01, area)
+}
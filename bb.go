@@ -45,6 +45,11 @@ func (b BB) Area() float64 {
 	return (b.r - b.l) * (b.t - b.b)
 }
 
+// Bottom returns the bottom edge of the bounding box.
+func (b BB) Bottom() float64 {
+	return b.b
+}
+
 // BBNew creates a 2D bounding box.
 func BBNew(l, b, r, t float64) BB {
 	return BB{l, b, r, t}
@@ -96,6 +101,11 @@ func (bb BB) IntersectsSegment(a, b Vect) bool {
 	return !math.IsInf(bb.SegmentQuery(a, b), 1)
 }
 
+// Left returns the left edge of the bounding box.
+func (b BB) Left() float64 {
+	return b.l
+}
+
 // Merge returns a bounding box that holds both bounding boxes.
 func (a BB) Merge(b BB) BB {
 	return BB{
@@ -111,6 +121,11 @@ func (a BB) MergedArea(b BB) float64 {
 	return (math.Max(a.r, b.r) - math.Min(a.l, b.l)) * (math.Max(a.t, b.t) - math.Min(a.b, b.b))
 }
 
+// Right returns the right edge of the bounding box.
+func (b BB) Right() float64 {
+	return b.r
+}
+
 // SegmentQuery returns the fraction along the segment query the BB is hit.
 // Returns math.Inf(1) if it doesn't hit.
 func (bb BB) SegmentQuery(a, b Vect) float64 {
@@ -162,6 +177,11 @@ func (b BB) String() string {
 	return fmt.Sprintf("(BB){l:%g, b:%g, r:%g, t:%g}", b.l, b.b, b.r, b.t)
 }
 
+// Top returns the top edge of the bounding box.
+func (b BB) Top() float64 {
+	return b.t
+}
+
 // WrapVect wraps a vector to a bounding box.
 func (bb BB) WrapVect(v Vect) Vect {
 	ix := math.Abs(bb.r - bb.l)
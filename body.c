@@ -29,16 +29,16 @@ extern void eachShapeBody(cpBody *b, cpShape *s, void *p);
 extern void updatePosition(cpBody *b, cpFloat dt);
 extern void updateVelocity(cpBody *b, cpVect gravity, cpFloat damping, cpFloat dt);
 
-inline void body_each_arbiter(cpBody *body, void *f) {
-	cpBodyEachArbiter(body, eachArbiterBody, f);
+inline void body_each_arbiter(cpBody *body, uintptr_t h) {
+	cpBodyEachArbiter(body, eachArbiterBody, (void *)h);
 }
 
-inline void body_each_constraint(cpBody *body, void *f) {
-	cpBodyEachConstraint(body, eachConstraintBody, f);
+inline void body_each_constraint(cpBody *body, uintptr_t h) {
+	cpBodyEachConstraint(body, eachConstraintBody, (void *)h);
 }
 
-inline void body_each_shape(cpBody *body, void *f) {
-	cpBodyEachShape(body, eachShapeBody, f);
+inline void body_each_shape(cpBody *body, uintptr_t h) {
+	cpBodyEachShape(body, eachShapeBody, (void *)h);
 }
 
 inline void body_set_position_func(cpBody *body, cpBool set) {
@@ -29,6 +29,8 @@ import "C"
 
 import (
 	"fmt"
+	"iter"
+	"sync"
 	"unsafe"
 )
 
@@ -46,8 +48,9 @@ type bodyData struct {
 ////////////////////////////////////////////////////////////////////////////////
 
 var (
-	bodyDataMap = make(map[Body]*bodyData)
-	nullBody    = Body(uintptr(0))
+	bodyDataMap   = make(map[Body]*bodyData)
+	bodyDataMutex sync.RWMutex
+	nullBody      = Body(uintptr(0))
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -110,45 +113,90 @@ func (b Body) ApplyImpulse(j, r Vect) {
 	C.cpBodyApplyImpulse(b.c(), j.c(), r.c())
 }
 
+// Arbiters returns an iterator over the arbiters currently active on the body.
+// Each arbiter reports the body as its first one while it's yielded.
+// The arbiters are collected before the first one is yielded, so the loop may
+// break early and mutate the body. But it must not remove the body or any shape
+// touching it from an unlocked space, as that frees arbiters yet to be yielded;
+// removals from a locked space, e.g. in a collision callback, are deferred and safe.
+func (b Body) Arbiters() iter.Seq[Arbiter] {
+	return func(yield func(Arbiter) bool) {
+		var arbiters []Arbiter
+		b.EachArbiter(func(_ Body, arb Arbiter) {
+			arbiters = append(arbiters, arb)
+		})
+
+		for _, arb := range arbiters {
+			if !yieldArbiter(b, arb, yield) {
+				return
+			}
+		}
+	}
+}
+
 // BodyNew creates a new body.
 func BodyNew(m, i float64) Body {
 	b := cpBody(C.cpBodyNew(C.cpFloat(m), C.cpFloat(i)))
-	bodyDataMap[b] = &bodyData{}
+	b.setData(&bodyData{})
 	return b
 }
 
 // BodyStaticNew creates a new static body.
 func BodyStaticNew() Body {
 	b := cpBody(C.cpBodyNewStatic())
-	bodyDataMap[b] = &bodyData{}
+	b.setData(&bodyData{})
 	return b
 }
 
+// Constraints returns an iterator over the constraints attached to the body.
+// The constraints are collected before the first one is yielded, so the loop may
+// break early and mutate the body.
+func (b Body) Constraints() iter.Seq[Constraint] {
+	return func(yield func(Constraint) bool) {
+		var constraints []Constraint
+		b.EachConstraint(func(_ Body, c Constraint) {
+			constraints = append(constraints, c)
+		})
+
+		for _, c := range constraints {
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
 // EachArbiter calls a callback function once for each arbiter which is currently
 // active on the body.
 func (b Body) EachArbiter(iter func(Body, Arbiter)) {
-	p := unsafe.Pointer(&iter)
-	C.body_each_arbiter(b.c(), p)
+	h := handleNew(iter)
+	defer handleDelete(h)
+	C.body_each_arbiter(b.c(), handleToC(h))
 }
 
 // EachConstraint calls a callback function once for each constraint attached
 // to the body and added to the space.
 func (b Body) EachConstraint(iter func(Body, Constraint)) {
-	p := unsafe.Pointer(&iter)
-	C.body_each_constraint(b.c(), p)
+	h := handleNew(iter)
+	defer handleDelete(h)
+	C.body_each_constraint(b.c(), handleToC(h))
 }
 
 // EachShape calls a callback function once for each shape attached to the body
 // and added to the space.
 func (b Body) EachShape(iter func(Body, Shape)) {
-	p := unsafe.Pointer(&iter)
-	C.body_each_shape(b.c(), p)
+	h := handleNew(iter)
+	defer handleDelete(h)
+	C.body_each_shape(b.c(), handleToC(h))
 }
 
 // Free removes a body.
+// A body removed from a locked space is freed once the removal is applied.
 func (b Body) Free() {
-	delete(bodyDataMap, b)
-	C.cpBodyFree(b.c())
+	freeAfterPendingOps(unsafe.Pointer(b.c()), func() {
+		b.setData(nil)
+		C.cpBodyFree(b.c())
+	})
 }
 
 // Force returns the force acting on the rigid body's center of gravity.
@@ -216,6 +264,45 @@ func (b Body) SetPosition(pos Vect) {
 	C.cpBodySetPos(b.c(), pos.c())
 }
 
+// RecomputeMassFromShapes sets the mass and the moment of inertia of the body
+// from its shapes, all of the given density, and returns their combined mass properties.
+// Only the shapes added to a space are attached to the body, sensor shapes are skipped.
+// The body is left unchanged if the shapes have no mass.
+// If recenter is true, the shapes are moved so that their center of gravity is at the
+// body origin and the body is moved so that they keep their place and velocity in the world.
+// Anchors of the constraints attached to the body aren't updated.
+func (b Body) RecomputeMassFromShapes(density float64, recenter bool) MassProperties {
+	var props MassProperties
+	b.EachShape(func(_ Body, sh Shape) {
+		if m, ok := sh.(massShape); ok && !sh.Sensor() {
+			props = props.Add(m.MassProperties(density))
+		}
+	})
+
+	if props.Mass <= 0.0 {
+		return props
+	}
+
+	if recenter && props.Centroid != Origin() {
+		offset := props.Centroid.Neg()
+		for sh := range b.Shapes() {
+			moveShape(sh, offset)
+		}
+
+		b.SetVelocity(b.VelocityAtLocalPoint(props.Centroid))
+		b.SetPosition(b.LocalToWorld(props.Centroid))
+		props.Centroid = Origin()
+
+		if s := b.Space(); 0 != s && !s.IsLocked() {
+			s.ReindexShapesForBody(b)
+		}
+	}
+
+	b.SetMass(props.Mass)
+	b.SetMoment(props.MomentAbout(Origin()))
+	return props
+}
+
 // ResetForces sets the forces and torque of a body to zero.
 func (b Body) ResetForces() {
 	C.cpBodyResetForces(b.c())
@@ -228,16 +315,34 @@ func (b Body) Rotation() Vect {
 
 // SetPositionFunc sets a function that is called to integrate the body's position.
 func (b Body) SetPositionFunc(f func(b Body, dt float64)) {
-	bodyDataMap[b].positionFunc = f
+	b.data().positionFunc = f
 	C.body_set_position_func(b.c(), boolToC(f != nil))
 }
 
 // SetVelocityFunc sets a function that is called to integrate the body's velocity.
 func (b Body) SetVelocityFunc(f func(b Body, gravity Vect, damping, dt float64)) {
-	bodyDataMap[b].velocityFunc = f
+	b.data().velocityFunc = f
 	C.body_set_velocity_func(b.c(), boolToC(f != nil))
 }
 
+// Shapes returns an iterator over the shapes attached to the body.
+// The shapes are collected before the first one is yielded, so the loop may
+// break early and mutate the body.
+func (b Body) Shapes() iter.Seq[Shape] {
+	return func(yield func(Shape) bool) {
+		var shapes []Shape
+		b.EachShape(func(_ Body, sh Shape) {
+			shapes = append(shapes, sh)
+		})
+
+		for _, sh := range shapes {
+			if !yield(sh) {
+				return
+			}
+		}
+	}
+}
+
 // Sleep forces a body to fall asleep immediately.
 func (b Body) Sleep() {
 	C.cpBodySleep(b.c())
@@ -280,14 +385,14 @@ func (b Body) UpdateVelocity(gravity Vect, damping float64, dt float64) {
 
 // UserData returns user defined data.
 func (b Body) UserData() interface{} {
-	return bodyDataMap[b].userData
+	return b.data().userData
 }
 
 // SetUserData sets user definable data pointer.
 // Generally this points to your the game object so you can access it
 // when given a Body reference in a callback.
 func (b Body) SetUserData(data interface{}) {
-	bodyDataMap[b].userData = data
+	b.data().userData = data
 }
 
 // Velocity returns the velocity of the rigid body's center of gravity.
@@ -347,21 +452,28 @@ func cpBody(b *C.cpBody) Body {
 	return Body(unsafe.Pointer(b))
 }
 
+// data returns the Go side state of the body.
+func (b Body) data() *bodyData {
+	bodyDataMutex.RLock()
+	defer bodyDataMutex.RUnlock()
+	return bodyDataMap[b]
+}
+
 //export eachArbiterBody
 func eachArbiterBody(b *C.cpBody, a *C.cpArbiter, p unsafe.Pointer) {
-	f := *(*func(Body, Arbiter))(p)
+	f := cpHandleValue(p).(func(Body, Arbiter))
 	f(cpBody(b), cpArbiter(a))
 }
 
 //export eachConstraintBody
 func eachConstraintBody(b *C.cpBody, c *C.cpConstraint, p unsafe.Pointer) {
-	f := *(*func(Body, Constraint))(p)
+	f := cpHandleValue(p).(func(Body, Constraint))
 	f(cpBody(b), cpConstraint(c))
 }
 
 //export eachShapeBody
 func eachShapeBody(b *C.cpBody, sh *C.cpShape, p unsafe.Pointer) {
-	f := *(*func(Body, Shape))(p)
+	f := cpHandleValue(p).(func(Body, Shape))
 	f(cpBody(b), cpShape(sh))
 }
 
@@ -370,14 +482,36 @@ func (b Body) removeFromSpace(s Space) {
 	s.RemoveBody(b)
 }
 
+// yieldArbiter yields an arbiter oriented so that it reports the body first,
+// as EachArbiter does, and restores its orientation afterwards.
+func yieldArbiter(b Body, arb Arbiter, yield func(Arbiter) bool) bool {
+	if a, _ := arb.Bodies(); a != b {
+		arb.swap()
+		defer arb.swap()
+	}
+	return yield(arb)
+}
+
+// setData sets the Go side state of the body, nil removes it.
+func (b Body) setData(d *bodyData) {
+	bodyDataMutex.Lock()
+	defer bodyDataMutex.Unlock()
+
+	if d == nil {
+		delete(bodyDataMap, b)
+	} else {
+		bodyDataMap[b] = d
+	}
+}
+
 //export updatePosition
 func updatePosition(b *C.cpBody, dt C.cpFloat) {
-	d := bodyDataMap[cpBody(b)]
+	d := cpBody(b).data()
 	d.positionFunc(cpBody(b), float64(dt))
 }
 
 //export updateVelocity
 func updateVelocity(b *C.cpBody, gravity C.cpVect, damping, dt C.cpFloat) {
-	d := bodyDataMap[cpBody(b)]
+	d := cpBody(b).data()
 	d.velocityFunc(cpBody(b), cpVect(gravity), float64(damping), float64(dt))
 }
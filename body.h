@@ -24,9 +24,9 @@ WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 #ifndef _GOCHIPMUNK_BODY_H
 #define _GOCHIPMUNK_BODY_H
 
-inline void body_each_arbiter(cpBody *body, void *f);
-inline void body_each_constraint(cpBody *body, void *f);
-inline void body_each_shape(cpBody *body, void *f);
+inline void body_each_arbiter(cpBody *body, uintptr_t h);
+inline void body_each_constraint(cpBody *body, uintptr_t h);
+inline void body_each_shape(cpBody *body, uintptr_t h);
 inline void body_set_position_func(cpBody *body, cpBool set);
 inline void body_set_velocity_func(cpBody *body, cpBool set);
 
@@ -25,6 +25,7 @@ WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 
 import (
 	"github.com/bmizerany/assert"
+	"math"
 	"testing"
 )
 
@@ -108,3 +109,87 @@ func Test_BodySetVelocityFunc(t *testing.T) {
 	b.Free()
 	s.Free()
 }
+
+func Test_BodyIterators(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	ground.SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	s.SetEnableContactGraph(true)
+
+	other := s.AddBody(BodyNew(1.0, 1.0))
+	s.AddConstraint(PivotJointNew(b, other, Origin()))
+	s.AddShape(CircleShapeNew(b, 0.5, Origin()))
+
+	n := 0
+	for sh := range b.Shapes() {
+		// shapes may be removed while iterating
+		s.RemoveShape(sh)
+		n++
+	}
+	assert.Equal(t, 2, n)
+
+	s.AddShape(ball)
+	for c := range b.Constraints() {
+		assert.Equal(t, other, c.B())
+	}
+
+	s.Step(1.0 / 60.0)
+
+	n = 0
+	for arb := range b.Arbiters() {
+		a, g := arb.Shapes()
+		assert.Equal(t, ball, a)
+		assert.Equal(t, ground, g)
+		n++
+		break
+	}
+	assert.Equal(t, 1, n)
+
+	// the orientation is restored after the loop
+	for arb := range ground.Body().Arbiters() {
+		a, _ := arb.Shapes()
+		assert.Equal(t, ground, a)
+	}
+
+	s.Free()
+}
+
+func Test_BodyRecomputeMassFromShapes(t *testing.T) {
+	// shapes are attached to a body when added to a space
+	s := SpaceNew()
+	defer s.Free()
+
+	b := s.AddBody(BodyNew(1.0, 1.0))
+	b.SetPosition(VectNew(10.0, 0.0))
+
+	left := CircleShapeNew(b, 1.0, VectNew(-1.0, 0.0))
+	right := CircleShapeNew(b, 1.0, VectNew(3.0, 0.0))
+	sensor := CircleShapeNew(b, 5.0, Origin())
+	sensor.SetSensor(true)
+	s.AddShape(left)
+	s.AddShape(right)
+	s.AddShape(sensor)
+
+	p := b.RecomputeMassFromShapes(1.0, false)
+	assert.T(t, nearlyEqual(2.0*math.Pi, b.Mass()))
+	assert.Equal(t, VectNew(1.0, 0.0), p.Centroid)
+	assert.T(t, nearlyEqual(9.0*math.Pi, p.Moment))
+	assert.T(t, nearlyEqual(9.0*math.Pi+2.0*math.Pi, b.Moment()))
+
+	b.SetAngularVelocity(2.0)
+	p = b.RecomputeMassFromShapes(1.0, true)
+	assert.Equal(t, Origin(), p.Centroid)
+	assert.T(t, nearlyEqual(9.0*math.Pi, b.Moment()))
+	assert.Equal(t, VectNew(11.0, 0.0), b.Position())
+	assert.Equal(t, VectNew(0.0, 2.0), b.Velocity())
+	assert.Equal(t, VectNew(-2.0, 0.0), left.Offset())
+	assert.Equal(t, VectNew(2.0, 0.0), right.Offset())
+}
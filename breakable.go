@@ -0,0 +1,159 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// BreakEvent describes a constraint which broke and was removed from its space.
+type BreakEvent struct {
+	Constraint Constraint
+	BodyA      Body
+	BodyB      Body
+	// PeakForce is the greatest force the constraint applied.
+	PeakForce float64
+}
+
+// Breakable makes a constraint break, i.e. be removed from its space, when the
+// force it applies (its impulse divided by the time step) exceeds a limit, either
+// at once or accumulated over time as fatigue.
+//
+// It's driven by the post-solve function of the constraint, so it only works
+// while the constraint is in a space. Setting another post-solve function on the
+// constraint disables it. A Breakable must only be used by the goroutine which
+// steps the space.
+type Breakable struct {
+	constraint   Constraint
+	breakForce   float64
+	fatigueForce float64
+	fatigueLimit float64
+	fatigue      float64
+	peakForce    float64
+	broken       bool
+	onBreak      func(BreakEvent)
+	postSolve    func(Constraint, Space)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// BreakableNew makes a constraint break when the force it applies exceeds
+// breakForce, zero meaning it only breaks by fatigue. A post-solve function
+// already set on the constraint is still called.
+func BreakableNew(c Constraint, breakForce float64) *Breakable {
+	base := cpConstraintBase(c.c())
+	b := &Breakable{
+		constraint: c,
+		breakForce: breakForce,
+		postSolve:  base.data().postSolveFunc,
+	}
+
+	base.SetPostSolveFunc(b.solved)
+	return b
+}
+
+// BreakForce returns the force above which the constraint breaks at once.
+func (b *Breakable) BreakForce() float64 {
+	return b.breakForce
+}
+
+// Broken returns true if the constraint broke.
+func (b *Breakable) Broken() bool {
+	return b.broken
+}
+
+// Constraint returns the breakable constraint.
+func (b *Breakable) Constraint() Constraint {
+	return b.constraint
+}
+
+// Fatigue returns the fatigue accumulated by the constraint.
+func (b *Breakable) Fatigue() float64 {
+	return b.fatigue
+}
+
+// PeakForce returns the greatest force the constraint applied.
+func (b *Breakable) PeakForce() float64 {
+	return b.peakForce
+}
+
+// SetBreakForce sets the force above which the constraint breaks at once,
+// zero meaning it only breaks by fatigue.
+func (b *Breakable) SetBreakForce(f float64) {
+	b.breakForce = f
+}
+
+// SetFatigue makes the constraint accumulate the force it applies above a force
+// over time (force x seconds) and break once the accumulated fatigue reaches a limit.
+// A zero limit disables fatigue.
+func (b *Breakable) SetFatigue(force, limit float64) {
+	b.fatigueForce = force
+	b.fatigueLimit = limit
+}
+
+// SetOnBreakFunc sets a callback function called when the constraint breaks,
+// after the step, once it's removed from the space.
+func (b *Breakable) SetOnBreakFunc(f func(BreakEvent)) {
+	b.onBreak = f
+}
+
+// breakConstraint removes the broken constraint from a space and reports it.
+func (b *Breakable) breakConstraint(s Space) {
+	c := b.constraint
+	if s.Contains(c.(SpaceObject)) {
+		s.RemoveConstraint(c)
+	}
+
+	if nil != b.onBreak {
+		b.onBreak(BreakEvent{c, c.A(), c.B(), b.peakForce})
+	}
+}
+
+// solved is the post-solve function of the constraint checking its force.
+func (b *Breakable) solved(c Constraint, s Space) {
+	if nil != b.postSolve {
+		b.postSolve(c, s)
+	}
+
+	dt := s.CurrentTimeStep()
+	if b.broken || dt <= 0.0 {
+		return
+	}
+
+	force := math.Abs(c.Impulse()) / dt
+	b.peakForce = math.Max(b.peakForce, force)
+
+	if b.fatigueLimit > 0.0 && force > b.fatigueForce {
+		b.fatigue += (force - b.fatigueForce) * dt
+	}
+
+	if (b.breakForce > 0.0 && force > b.breakForce) || (b.fatigueLimit > 0.0 && b.fatigue >= b.fatigueLimit) {
+		b.broken = true
+		s.AddPostStepCallback(func(s Space, _ interface{}) {
+			b.breakConstraint(s)
+		}, b)
+	}
+}
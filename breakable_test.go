@@ -0,0 +1,94 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_BreakableForce(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	b := s.AddBody(BodyNew(1.0, 1.0))
+	j := PivotJointNew(s.StaticBody(), b, Origin())
+	s.AddConstraint(j)
+
+	postSolved := 0
+	j.SetPostSolveFunc(func(Constraint, Space) {
+		postSolved++
+	})
+
+	var events []BreakEvent
+	br := BreakableNew(j, 50.0)
+	br.SetOnBreakFunc(func(ev BreakEvent) {
+		assert.T(t, !s.IsLocked())
+		events = append(events, ev)
+	})
+
+	s.Step(1.0 / 60.0)
+	assert.T(t, br.Broken())
+	assert.T(t, !s.Contains(j))
+	assert.Equal(t, 1, postSolved)
+
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, Constraint(j), events[0].Constraint)
+	assert.Equal(t, s.StaticBody(), events[0].BodyA)
+	assert.Equal(t, b, events[0].BodyB)
+	assert.T(t, events[0].PeakForce > 50.0)
+
+	s.Step(1.0 / 60.0)
+	assert.Equal(t, 1, len(events))
+
+	s.Free()
+}
+
+func Test_BreakableFatigue(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	b := s.AddBody(BodyNew(1.0, 1.0))
+	j := PivotJointNew(s.StaticBody(), b, Origin())
+	s.AddConstraint(j)
+
+	br := BreakableNew(j, 0.0)
+	br.SetFatigue(50.0, 5.0)
+
+	for i := 0; i < 3; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.T(t, !br.Broken())
+	assert.T(t, br.Fatigue() > 0.0)
+	assert.T(t, s.Contains(j))
+
+	for i := 0; i < 60 && !br.Broken(); i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.T(t, br.Broken())
+	assert.T(t, br.Fatigue() >= 5.0)
+	assert.T(t, !s.Contains(j))
+
+	s.Free()
+}
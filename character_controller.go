@@ -0,0 +1,276 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CharacterController moves a platformer character: a body with an infinite
+// moment and a shape, which velocity is integrated by the controller.
+//
+// While on the ground, the character walks by setting the surface velocity of its
+// shape, so friction makes it inherit the velocity of moving platforms. In the air
+// it accelerates towards the walking speed by itself. Ground and walls are found
+// from the arbiters of the body each step, before the velocity is integrated.
+type CharacterController struct {
+	space        Space
+	body         Body
+	shape        Shape
+	opts         CharacterOptions
+	oneWay       *OneWayFilter
+	oneWayTypes  []CollisionType
+	move         float64
+	jumpBuffer   float64
+	airTime      float64
+	grounded     bool
+	groundBody   Body
+	groundNormal Vect
+	groundPoint  Vect
+	wall         bool
+	wallNormal   Vect
+
+	// the friction and the surface velocity of the shape before the controller
+	friction        float64
+	surfaceVelocity Vect
+}
+
+// CharacterOptions configure a character controller.
+type CharacterOptions struct {
+	// Up is the up direction of the character, zero means the opposite of the
+	// gravity, or the positive y axis without gravity.
+	Up Vect
+	// Speed is the walking speed.
+	Speed float64
+	// GroundAcceleration and AirAcceleration are how fast the character reaches
+	// the walking speed on the ground and in the air.
+	GroundAcceleration float64
+	AirAcceleration    float64
+	// JumpHeight is how high the character jumps.
+	JumpHeight float64
+	// MaxSlope is the steepest angle of the ground the character stands on,
+	// steeper surfaces are walls. Zero means 45 degrees.
+	MaxSlope float64
+	// CoyoteTime is how long after leaving the ground the character may still jump.
+	CoyoteTime float64
+	// JumpBuffer is how long a jump requested in the air is remembered, so it's
+	// done if the character lands in time.
+	JumpBuffer float64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Body returns the body of the character.
+func (c *CharacterController) Body() Body {
+	return c.body
+}
+
+// CharacterControllerNew creates a controller of a body with a shape in a space.
+// The moment of the body is set to infinity, so it doesn't rotate. The controller
+// owns the friction and the surface velocity of the shape until it's freed.
+func CharacterControllerNew(s Space, body Body, shape Shape, opts CharacterOptions) *CharacterController {
+	if 0.0 == opts.MaxSlope {
+		opts.MaxSlope = math.Pi / 4.0
+	}
+
+	c := &CharacterController{
+		space:           s,
+		body:            body,
+		shape:           shape,
+		opts:            opts,
+		airTime:         math.Inf(1),
+		friction:        shape.Friction(),
+		surfaceVelocity: shape.SurfaceVelocity(),
+	}
+	body.SetMoment(math.Inf(1))
+	body.SetVelocityFunc(c.updateVelocity)
+	return c
+}
+
+// DropThrough lets the character fall through one-way platforms for a timeout.
+func (c *CharacterController) DropThrough(timeout float64) {
+	if nil != c.oneWay {
+		c.oneWay.DropThrough(c.body, timeout)
+	}
+}
+
+// Free detaches the controller from its body, restores the friction and the
+// surface velocity of its shape and removes its collision filters.
+// The body keeps its infinite moment.
+func (c *CharacterController) Free() {
+	c.body.SetVelocityFunc(nil)
+	c.shape.SetFriction(c.friction)
+	c.shape.SetSurfaceVelocity(c.surfaceVelocity)
+	for _, t := range c.oneWayTypes {
+		c.space.RemoveCollisionFilter(t, c.shape.CollisionType(), c.oneWay)
+	}
+	c.oneWayTypes = nil
+}
+
+// GroundBody returns the body the character stands on, or 0 if it's in the air.
+func (c *CharacterController) GroundBody() Body {
+	if !c.grounded {
+		return 0
+	}
+	return c.groundBody
+}
+
+// GroundNormal returns the normal of the ground the character stands on,
+// pointing from the ground towards the character.
+func (c *CharacterController) GroundNormal() Vect {
+	return c.groundNormal
+}
+
+// GroundVelocity returns the velocity of the ground where the character stands on it.
+func (c *CharacterController) GroundVelocity() Vect {
+	if !c.grounded {
+		return Origin()
+	}
+	return c.groundBody.VelocityAtWorldPoint(c.groundPoint)
+}
+
+// IsGrounded returns true if the character stood on the ground in the last step.
+func (c *CharacterController) IsGrounded() bool {
+	return c.grounded
+}
+
+// IsOnWall returns true if the character touched a wall in the last step.
+func (c *CharacterController) IsOnWall() bool {
+	return c.wall
+}
+
+// Jump requests a jump. It's done in the next step if the character is on the
+// ground or was within the coyote time, otherwise it's buffered for the jump
+// buffer time.
+func (c *CharacterController) Jump() {
+	c.jumpBuffer = math.Max(c.opts.JumpBuffer, math.SmallestNonzeroFloat64)
+}
+
+// SetMove sets the walking direction, from -1 (left) to 1 (right) relative to
+// the up direction.
+func (c *CharacterController) SetMove(move float64) {
+	c.move = math.Max(-1.0, math.Min(1.0, move))
+	c.body.Activate()
+}
+
+// SetOneWayType makes shapes of a collision type one-way platforms for the
+// character: it passes through them unless it lands on them from above.
+// The shape of the character needs a collision type of its own. The platforms
+// are collidable from the up direction of the character when it's called.
+func (c *CharacterController) SetOneWayType(t CollisionType) {
+	if nil == c.oneWay {
+		c.oneWay = OneWayFilterNew(c.up(c.space.Gravity()))
+	}
+	c.space.AddCollisionFilter(t, c.shape.CollisionType(), c.oneWay)
+	c.oneWayTypes = append(c.oneWayTypes, t)
+}
+
+// Shape returns the shape of the character.
+func (c *CharacterController) Shape() Shape {
+	return c.shape
+}
+
+// WallNormal returns the normal of the wall the character touches, pointing from
+// the wall towards the character.
+func (c *CharacterController) WallNormal() Vect {
+	return c.wallNormal
+}
+
+// up returns the up direction of the character.
+func (c *CharacterController) up(gravity Vect) Vect {
+	switch {
+	case c.opts.Up != Origin():
+		return c.opts.Up.Div(c.opts.Up.Length())
+	case gravity != Origin():
+		return gravity.Div(-gravity.Length())
+	}
+	return VectNew(0.0, 1.0)
+}
+
+// updateGround finds the ground and the walls the character touches.
+func (c *CharacterController) updateGround(up Vect) {
+	minGround := math.Cos(c.opts.MaxSlope)
+	c.grounded, c.wall = false, false
+	c.groundNormal, c.wallNormal = Origin(), Origin()
+
+	best, wallBest := minGround, minGround
+	for arb := range c.body.Arbiters() {
+		if 0 == arb.Count() {
+			continue
+		}
+
+		_, other := arb.Bodies()
+		n := arb.Normal(0).Neg()
+		d := n.Dot(up)
+
+		switch {
+		case d >= best:
+			best = d
+			c.grounded, c.groundBody, c.groundNormal, c.groundPoint = true, other, n, arb.Point(0)
+		case math.Abs(d) < wallBest:
+			wallBest = math.Abs(d)
+			c.wall, c.wallNormal = true, n
+		}
+	}
+}
+
+// updateVelocity is the velocity function of the body.
+func (c *CharacterController) updateVelocity(b Body, gravity Vect, damping, dt float64) {
+	up := c.up(gravity)
+	right := VectNew(up.Y, -up.X)
+	c.updateGround(up)
+
+	if c.grounded {
+		c.airTime = 0.0
+	} else {
+		c.airTime += dt
+	}
+
+	v := b.Velocity()
+	if c.jumpBuffer > 0.0 && c.airTime <= c.opts.CoyoteTime {
+		jump := math.Sqrt(2.0 * c.opts.JumpHeight * gravity.Length())
+		v = v.Sub(up.Mul(v.Dot(up))).Add(up.Mul(c.GroundVelocity().Dot(up) + jump))
+		c.jumpBuffer = 0.0
+		c.airTime = math.Inf(1)
+	}
+	c.jumpBuffer -= dt
+
+	target := c.move * c.opts.Speed
+	if c.grounded {
+		c.shape.SetSurfaceVelocity(right.Mul(-target))
+		if g := gravity.Length(); g > 0.0 {
+			c.shape.SetFriction(c.opts.GroundAcceleration / g)
+		}
+	} else {
+		c.shape.SetSurfaceVelocity(Origin())
+		vx := v.Dot(right)
+		vx += math.Max(-c.opts.AirAcceleration*dt, math.Min(c.opts.AirAcceleration*dt, target-vx))
+		v = v.Add(right.Mul(vx - v.Dot(right)))
+	}
+
+	b.SetVelocity(v)
+	b.UpdateVelocity(gravity, damping, dt)
+}
@@ -0,0 +1,232 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"math"
+	"testing"
+)
+
+// characterOptions returns the options of a character jumping 4 units high.
+func characterOptions() CharacterOptions {
+	return CharacterOptions{
+		Speed:              10.0,
+		GroundAcceleration: 500.0,
+		AirAcceleration:    200.0,
+		JumpHeight:         4.0,
+		CoyoteTime:         0.1,
+		JumpBuffer:         0.1,
+	}
+}
+
+func stepCharacter(s Space, steps int, f func()) {
+	for i := 0; i < steps; i++ {
+		s.Step(1.0 / 60.0)
+		if nil != f {
+			f()
+		}
+	}
+}
+
+func Test_CharacterControllerWalk(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := SegmentShapeNew(s.StaticBody(), VectNew(-100.0, 0.0), VectNew(100.0, 0.0), 0.0)
+	ground.SetFriction(1.0)
+	s.AddStaticShape(ground)
+
+	wall := SegmentShapeNew(s.StaticBody(), VectNew(5.0, 0.0), VectNew(5.0, 10.0), 0.0)
+	wall.SetFriction(1.0)
+	s.AddStaticShape(wall)
+
+	body := s.AddBody(BodyNew(1.0, 1.0))
+	body.SetPosition(VectNew(0.0, 1.0))
+	shape := s.AddShape(BoxShapeNew(body, 1.0, 2.0))
+	shape.SetCollisionType(1)
+
+	c := CharacterControllerNew(s, body, shape, characterOptions())
+
+	stepCharacter(s, 30, nil)
+	assert.T(t, c.IsGrounded())
+	assert.Equal(t, s.StaticBody(), c.GroundBody())
+	assert.Tf(t, c.GroundNormal().Y > 0.99, "%v", c.GroundNormal())
+	assert.T(t, !c.IsOnWall())
+
+	c.SetMove(1.0)
+	stepCharacter(s, 15, nil)
+	assert.Tf(t, math.Abs(c.Body().Velocity().X-10.0) < 0.5, "%v", c.Body().Velocity())
+
+	stepCharacter(s, 60, nil)
+	assert.T(t, c.IsGrounded())
+	assert.T(t, c.IsOnWall())
+	assert.Tf(t, c.WallNormal().X < -0.99, "%v", c.WallNormal())
+
+	// the shape gets back what the controller changed
+	assert.T(t, c.Shape().SurfaceVelocity() != Origin())
+	c.Free()
+	assert.Equal(t, 0.0, c.Shape().Friction())
+	assert.Equal(t, Origin(), c.Shape().SurfaceVelocity())
+
+	s.Free()
+}
+
+func Test_CharacterControllerJump(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := SegmentShapeNew(s.StaticBody(), VectNew(-100.0, 0.0), VectNew(100.0, 0.0), 0.0)
+	ground.SetFriction(1.0)
+	s.AddStaticShape(ground)
+
+	body := s.AddBody(BodyNew(1.0, 1.0))
+	body.SetPosition(VectNew(0.0, 1.0))
+	shape := s.AddShape(BoxShapeNew(body, 1.0, 2.0))
+	shape.SetCollisionType(1)
+
+	c := CharacterControllerNew(s, body, shape, characterOptions())
+
+	stepCharacter(s, 30, nil)
+	c.Jump()
+
+	top := 0.0
+	stepCharacter(s, 30, func() { top = math.Max(top, c.Body().Position().Y) })
+	assert.Tf(t, math.Abs(top-5.0) < 0.5, "jumped to %v", top)
+	assert.T(t, !c.IsGrounded())
+
+	// a jump requested just before landing is done on landing
+	for c.Body().Position().Y > 1.5 {
+		stepCharacter(s, 1, nil)
+	}
+	c.Jump()
+
+	top = 0.0
+	stepCharacter(s, 30, func() { top = math.Max(top, c.Body().Position().Y) })
+	assert.Tf(t, top > 4.0, "buffered jump to %v", top)
+
+	c.Free()
+	s.Free()
+}
+
+func Test_CharacterControllerCoyoteTime(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ledge := SegmentShapeNew(s.StaticBody(), VectNew(-100.0, 0.0), VectNew(0.0, 0.0), 0.0)
+	ledge.SetFriction(1.0)
+	s.AddStaticShape(ledge)
+
+	body := s.AddBody(BodyNew(1.0, 1.0))
+	body.SetPosition(VectNew(-2.0, 1.0))
+	shape := s.AddShape(BoxShapeNew(body, 1.0, 2.0))
+	shape.SetCollisionType(1)
+
+	c := CharacterControllerNew(s, body, shape, characterOptions())
+	stepCharacter(s, 30, nil)
+
+	// walk off the ledge and jump right after
+	c.SetMove(1.0)
+	for c.IsGrounded() {
+		stepCharacter(s, 1, nil)
+	}
+	stepCharacter(s, 2, nil)
+	c.Jump()
+	stepCharacter(s, 1, nil)
+	assert.Tf(t, c.Body().Velocity().Y > 0.0, "coyote jump failed: %v", c.Body().Velocity())
+
+	// too late to jump in the air
+	stepCharacter(s, 60, nil)
+	c.Jump()
+	stepCharacter(s, 1, nil)
+	assert.Tf(t, c.Body().Velocity().Y < 0.0, "jumped in the air: %v", c.Body().Velocity())
+
+	c.Free()
+	s.Free()
+}
+
+func Test_CharacterControllerMovingPlatform(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	body := s.AddBody(BodyNew(1.0, 1.0))
+	body.SetPosition(VectNew(0.0, 1.0))
+	shape := s.AddShape(BoxShapeNew(body, 1.0, 2.0))
+	shape.SetCollisionType(1)
+
+	c := CharacterControllerNew(s, body, shape, characterOptions())
+
+	platform := BodyNew(math.Inf(1), math.Inf(1))
+	platform.SetVelocity(VectNew(5.0, 0.0))
+
+	sh := SegmentShapeNew(platform, VectNew(-10.0, 0.0), VectNew(10.0, 0.0), 0.0)
+	sh.SetFriction(1.0)
+	s.AddShape(sh)
+
+	stepCharacter(s, 60, func() { platform.UpdatePosition(1.0 / 60.0) })
+	assert.Equal(t, platform, c.GroundBody())
+	assert.Equal(t, VectNew(5.0, 0.0), c.GroundVelocity())
+	assert.Tf(t, math.Abs(c.Body().Velocity().X-5.0) < 0.1, "%v", c.Body().Velocity())
+	assert.Tf(t, math.Abs(c.Body().Position().X-platform.Position().X) < 1.0,
+		"character %v left platform %v", c.Body().Position(), platform.Position())
+
+	c.Free()
+	s.Free()
+}
+
+func Test_CharacterControllerOneWayPlatform(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := SegmentShapeNew(s.StaticBody(), VectNew(-100.0, 0.0), VectNew(100.0, 0.0), 0.0)
+	ground.SetFriction(1.0)
+	s.AddStaticShape(ground)
+
+	body := s.AddBody(BodyNew(1.0, 1.0))
+	body.SetPosition(VectNew(0.0, 1.0))
+	shape := s.AddShape(BoxShapeNew(body, 1.0, 2.0))
+	shape.SetCollisionType(1)
+
+	c := CharacterControllerNew(s, body, shape, characterOptions())
+
+	platform := SegmentShapeNew(s.StaticBody(), VectNew(-5.0, 3.0), VectNew(5.0, 3.0), 0.0)
+	platform.SetCollisionType(2)
+	s.AddStaticShape(platform)
+	c.SetOneWayType(2)
+
+	stepCharacter(s, 30, nil)
+	c.Jump()
+	stepCharacter(s, 90, nil)
+
+	assert.T(t, c.IsGrounded())
+	assert.Tf(t, math.Abs(c.Body().Position().Y-4.0) < 0.1, "character didn't land on the platform: %v", c.Body().Position())
+
+	c.DropThrough(0.2)
+	stepCharacter(s, 60, nil)
+	assert.T(t, c.IsGrounded())
+	assert.Tf(t, math.Abs(c.Body().Position().Y-1.0) < 0.1, "character didn't drop through: %v", c.Body().Position())
+
+	c.Free()
+	s.Free()
+}
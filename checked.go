@@ -0,0 +1,573 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CheckedSpace is a space whose methods for adding and removing objects check
+// the conditions Chipmunk asserts on and return errors instead of aborting the
+// process. All the other methods are the ones of Space.
+// Unlike Space, it doesn't defer additions and removals while the space is locked,
+// since queued operations can't be checked in advance, it returns ErrSpaceLocked instead.
+type CheckedSpace struct {
+	Space
+}
+
+// shapeGroup is a space object made of shapes, added and removed as a whole.
+type shapeGroup interface {
+	SpaceObject
+	shapes() []Shape
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Errors returned by the checked functions, wrapped with the operation which failed.
+var (
+	ErrAlreadyInSpace  = errors.New("object is already in the space")
+	ErrInOtherSpace    = errors.New("object is in another space")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrNilObject       = errors.New("object is nil")
+	ErrNotInSpace      = errors.New("object is not in the space")
+	ErrSpaceLocked     = errors.New("space is locked")
+	ErrStaticBody      = errors.New("static bodies can't be added to or removed from a space")
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Add adds an object to the space. Objects made of shapes, like CompoundPolyShape
+// and SegmentChain, are added as a whole or not at all.
+func (s CheckedSpace) Add(obj SpaceObject) error {
+	switch o := obj.(type) {
+	case Body:
+		return s.AddBody(o)
+	case Shape:
+		return s.AddShape(o)
+	case Constraint:
+		return s.AddConstraint(o)
+	case shapeGroup:
+		return s.addShapes(o.shapes())
+	}
+
+	return checkError("Add", ErrNilObject)
+}
+
+// AddBody adds a rigid body to the space.
+func (s CheckedSpace) AddBody(b Body) error {
+	if 0 != b && b.IsStatic() {
+		return checkError("AddBody", ErrStaticBody)
+	}
+	if err := s.checkAdd(b, "body"); err != nil {
+		return checkError("AddBody", err)
+	}
+
+	s.Space.AddBody(b)
+	return nil
+}
+
+// AddConstraint adds a constraint to the space.
+func (s CheckedSpace) AddConstraint(c Constraint) error {
+	if err := s.checkAdd(c, "constraint"); err != nil {
+		return checkError("AddConstraint", err)
+	}
+	if 0 == c.A() || 0 == c.B() {
+		return checkError("AddConstraint", fmt.Errorf("%w: constraint body", ErrNilObject))
+	}
+
+	s.Space.AddConstraint(c)
+	return nil
+}
+
+// AddShape adds a collision shape to the space.
+// If the shape is attached to a static body, it's added as a static shape.
+func (s CheckedSpace) AddShape(sh Shape) error {
+	if err := s.checkAddShape(sh); err != nil {
+		return checkError("AddShape", err)
+	}
+
+	s.Space.AddShape(sh)
+	return nil
+}
+
+// AddStaticShape explicitly adds a shape as a static shape to the space.
+// The shape must be attached to a static or rogue body.
+func (s CheckedSpace) AddStaticShape(sh Shape) error {
+	if err := s.checkAdd(sh, "shape"); err != nil {
+		return checkError("AddStaticShape", err)
+	}
+	if 0 == sh.Body() {
+		return checkError("AddStaticShape", fmt.Errorf("%w: shape body", ErrNilObject))
+	}
+	if !sh.Body().IsRogue() {
+		return checkError("AddStaticShape",
+			fmt.Errorf("%w: static shape attached to a dynamic body", ErrInvalidArgument))
+	}
+
+	s.Space.AddStaticShape(sh)
+	return nil
+}
+
+// Checked returns the space with checked methods for adding and removing objects.
+func (s Space) Checked() CheckedSpace {
+	return CheckedSpace{s}
+}
+
+// Remove removes an object from the space. Objects made of shapes are removed
+// as a whole or not at all.
+func (s CheckedSpace) Remove(obj SpaceObject) error {
+	switch o := obj.(type) {
+	case Body:
+		return s.RemoveBody(o)
+	case Shape:
+		return s.RemoveShape(o)
+	case Constraint:
+		return s.RemoveConstraint(o)
+	case shapeGroup:
+		return s.removeShapes(o.shapes())
+	}
+
+	return checkError("Remove", ErrNilObject)
+}
+
+// RemoveBody removes a rigid body from the space.
+func (s CheckedSpace) RemoveBody(b Body) error {
+	if 0 != b && b == s.StaticBody() {
+		return checkError("RemoveBody", ErrStaticBody)
+	}
+	if err := s.checkRemove(b, "body"); err != nil {
+		return checkError("RemoveBody", err)
+	}
+
+	s.Space.RemoveBody(b)
+	return nil
+}
+
+// RemoveConstraint removes a constraint from the space.
+func (s CheckedSpace) RemoveConstraint(c Constraint) error {
+	if err := s.checkRemove(c, "constraint"); err != nil {
+		return checkError("RemoveConstraint", err)
+	}
+
+	s.Space.RemoveConstraint(c)
+	return nil
+}
+
+// RemoveShape removes a collision shape from the space.
+func (s CheckedSpace) RemoveShape(sh Shape) error {
+	if err := s.checkRemove(sh, "shape"); err != nil {
+		return checkError("RemoveShape", err)
+	}
+
+	s.Space.RemoveShape(sh)
+	return nil
+}
+
+// RemoveStaticShape removes a static collision shape from the space.
+func (s CheckedSpace) RemoveStaticShape(sh Shape) error {
+	if err := s.checkRemove(sh, "shape"); err != nil {
+		return checkError("RemoveStaticShape", err)
+	}
+
+	s.Space.RemoveStaticShape(sh)
+	return nil
+}
+
+// addShapes adds shapes to the space, none of them if any can't be added.
+func (s CheckedSpace) addShapes(shapes []Shape) error {
+	for _, sh := range shapes {
+		if err := s.checkAddShape(sh); err != nil {
+			return checkError("Add", err)
+		}
+	}
+
+	for _, sh := range shapes {
+		s.Space.AddShape(sh)
+	}
+	return nil
+}
+
+// checkAdd checks an object of specific kind can be added to the space.
+func (s CheckedSpace) checkAdd(obj interface{}, kind string) error {
+	space, err := objectSpace(obj, kind)
+
+	switch {
+	case err != nil:
+		return err
+	case space == s.Space:
+		return fmt.Errorf("%w: %s", ErrAlreadyInSpace, kind)
+	case 0 != space:
+		return fmt.Errorf("%w: %s", ErrInOtherSpace, kind)
+	case s.IsLocked():
+		return ErrSpaceLocked
+	}
+
+	return nil
+}
+
+// checkAddShape checks a shape can be added to the space.
+func (s CheckedSpace) checkAddShape(sh Shape) error {
+	if err := s.checkAdd(sh, "shape"); err != nil {
+		return err
+	}
+	if 0 == sh.Body() {
+		return fmt.Errorf("%w: shape body", ErrNilObject)
+	}
+	return nil
+}
+
+// checkRemove checks an object of specific kind can be removed from the space.
+func (s CheckedSpace) checkRemove(obj interface{}, kind string) error {
+	space, err := objectSpace(obj, kind)
+
+	switch {
+	case err != nil:
+		return err
+	case space != s.Space:
+		return fmt.Errorf("%w: %s", ErrNotInSpace, kind)
+	case s.IsLocked():
+		return ErrSpaceLocked
+	}
+
+	return nil
+}
+
+// checkError wraps an error of a checked function.
+func checkError(op string, err error) error {
+	return fmt.Errorf("chipmunk: %s: %w", op, err)
+}
+
+// removeShapes removes shapes from the space, none of them if any can't be removed.
+func (s CheckedSpace) removeShapes(shapes []Shape) error {
+	for _, sh := range shapes {
+		if err := s.checkRemove(sh, "shape"); err != nil {
+			return checkError("Remove", err)
+		}
+	}
+
+	for _, sh := range shapes {
+		s.Space.RemoveShape(sh)
+	}
+	return nil
+}
+
+// objectSpace returns the space of a body, shape or constraint.
+func objectSpace(obj interface{}, kind string) (Space, error) {
+	switch o := obj.(type) {
+	case Body:
+		if 0 != o {
+			return o.Space(), nil
+		}
+	case Shape:
+		if nil != o && nil != o.c() {
+			return cpshape(o.c()).Space(), nil
+		}
+	case Constraint:
+		if nil != o && nil != o.c() {
+			return cpConstraintBase(o.c()).Space(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrNilObject, kind)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// BoxShapeNewChecked is BoxShapeNew returning an error for a non-positive size.
+func BoxShapeNewChecked(b Body, width, height float64) (Shape, error) {
+	if err := checkPositive("BoxShapeNew", "width", width); err != nil {
+		return nil, err
+	}
+	if err := checkPositive("BoxShapeNew", "height", height); err != nil {
+		return nil, err
+	}
+
+	return BoxShapeNew(b, width, height), nil
+}
+
+// BoxShapeNew2Checked is BoxShapeNew2 returning an error for an empty box.
+func BoxShapeNew2Checked(b Body, box BB) (Shape, error) {
+	return BoxShapeNew3Checked(b, box, 0.0)
+}
+
+// BoxShapeNew3Checked is BoxShapeNew3 returning an error for an empty box or invalid radius.
+func BoxShapeNew3Checked(b Body, box BB, radius float64) (Shape, error) {
+	if err := checkPositive("BoxShapeNew", "width", box.r-box.l); err != nil {
+		return nil, err
+	}
+	if err := checkPositive("BoxShapeNew", "height", box.t-box.b); err != nil {
+		return nil, err
+	}
+	if err := checkNonNegative("BoxShapeNew", "radius", radius); err != nil {
+		return nil, err
+	}
+
+	return BoxShapeNew3(b, box, radius), nil
+}
+
+// CircleShapeNewChecked is CircleShapeNew returning an error for an invalid radius.
+func CircleShapeNewChecked(body Body, radius float64, offset Vect) (CircleShape, error) {
+	if err := checkNonNegative("CircleShapeNew", "radius", radius); err != nil {
+		return CircleShape{}, err
+	}
+
+	return CircleShapeNew(body, radius, offset), nil
+}
+
+// PolyShapeNewChecked is PolyShapeNew returning an error for vertices that are
+// not a convex polygon with a clockwise winding.
+func PolyShapeNewChecked(b Body, verts []Vect, offset Vect) (PolyShape, error) {
+	return PolyShapeNew2Checked(b, verts, offset, 0.0)
+}
+
+// PolyShapeNew2Checked is PolyShapeNew2 returning an error for vertices that are
+// not a convex polygon with a clockwise winding or an invalid radius.
+func PolyShapeNew2Checked(b Body, verts []Vect, offset Vect, radius float64) (PolyShape, error) {
+	if err := checkVerts("PolyShapeNew", verts); err != nil {
+		return PolyShape{}, err
+	}
+	if err := checkNonNegative("PolyShapeNew", "radius", radius); err != nil {
+		return PolyShape{}, err
+	}
+
+	return PolyShapeNew2(b, verts, offset, radius), nil
+}
+
+// SegmentShapeNewChecked is SegmentShapeNew returning an error for an invalid radius.
+func SegmentShapeNewChecked(body Body, a, b Vect, radius float64) (SegmentShape, error) {
+	if err := checkNonNegative("SegmentShapeNew", "radius", radius); err != nil {
+		return SegmentShape{}, err
+	}
+
+	return SegmentShapeNew(body, a, b, radius), nil
+}
+
+// checkNonNegative checks a value is finite and non-negative.
+func checkNonNegative(op, name string, v float64) error {
+	if !(v >= 0.0) || math.IsInf(v, 1) {
+		return checkError(op, fmt.Errorf("%w: %s %g must be finite and non-negative", ErrInvalidArgument, name, v))
+	}
+	return nil
+}
+
+// checkPositive checks a value is finite and positive.
+func checkPositive(op, name string, v float64) error {
+	if !(v > 0.0) || math.IsInf(v, 1) {
+		return checkError(op, fmt.Errorf("%w: %s %g must be finite and positive", ErrInvalidArgument, name, v))
+	}
+	return nil
+}
+
+// checkVerts checks vertices make a convex polygon with a clockwise winding.
+func checkVerts(op string, verts []Vect) error {
+	if len(verts) < 3 {
+		return checkError(op, fmt.Errorf("%w: polygon needs at least 3 vertices, got %d",
+			ErrInvalidArgument, len(verts)))
+	}
+
+	for _, v := range verts {
+		if math.IsNaN(v.X) || math.IsNaN(v.Y) || math.IsInf(v.X, 0) || math.IsInf(v.Y, 0) {
+			return checkError(op, fmt.Errorf("%w: polygon vertex %v is not finite", ErrInvalidArgument, v))
+		}
+	}
+
+	if !PolyValidate(verts) {
+		return checkError(op, fmt.Errorf("%w: polygon is concave or not wound clockwise",
+			ErrInvalidArgument))
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DampedRotarySpringNewChecked is DampedRotarySpringNew returning an error for
+// invalid bodies, stiffness or damping.
+func DampedRotarySpringNewChecked(a, b Body, restAngle, stiffness, damping float64) (DampedRotarySpring, error) {
+	const op = "DampedRotarySpringNew"
+	if err := checkSpring(op, a, b, stiffness, damping); err != nil {
+		return DampedRotarySpring{}, err
+	}
+
+	return DampedRotarySpringNew(a, b, restAngle, stiffness, damping), nil
+}
+
+// DampedSpringNewChecked is DampedSpringNew returning an error for invalid bodies,
+// rest length, stiffness or damping.
+func DampedSpringNewChecked(a, b Body, anchr1, anchr2 Vect,
+	restLength, stiffness, damping float64) (DampedSpring, error) {
+
+	const op = "DampedSpringNew"
+	if err := checkSpring(op, a, b, stiffness, damping); err != nil {
+		return DampedSpring{}, err
+	}
+	if err := checkNonNegative(op, "rest length", restLength); err != nil {
+		return DampedSpring{}, err
+	}
+
+	return DampedSpringNew(a, b, anchr1, anchr2, restLength, stiffness, damping), nil
+}
+
+// GearJointNewChecked is GearJointNew returning an error for invalid bodies or a zero ratio.
+func GearJointNewChecked(a, b Body, phase, ratio float64) (GearJoint, error) {
+	const op = "GearJointNew"
+	if err := checkBodies(op, a, b); err != nil {
+		return GearJoint{}, err
+	}
+	if 0.0 == ratio || math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+		return GearJoint{}, checkError(op, fmt.Errorf("%w: ratio %g must be finite and non-zero",
+			ErrInvalidArgument, ratio))
+	}
+
+	return GearJointNew(a, b, phase, ratio), nil
+}
+
+// GrooveJointNewChecked is GrooveJointNew returning an error for invalid bodies.
+func GrooveJointNewChecked(a, b Body, grooveA, grooveB, anchr2 Vect) (GrooveJoint, error) {
+	if err := checkBodies("GrooveJointNew", a, b); err != nil {
+		return GrooveJoint{}, err
+	}
+
+	return GrooveJointNew(a, b, grooveA, grooveB, anchr2), nil
+}
+
+// PinJointNewChecked is PinJointNew returning an error for invalid bodies.
+func PinJointNewChecked(a, b Body, anchr1, anchr2 Vect) (PinJoint, error) {
+	if err := checkBodies("PinJointNew", a, b); err != nil {
+		return PinJoint{}, err
+	}
+
+	return PinJointNew(a, b, anchr1, anchr2), nil
+}
+
+// PivotJointNewChecked is PivotJointNew returning an error for invalid bodies.
+func PivotJointNewChecked(a, b Body, pivot Vect) (PivotJoint, error) {
+	if err := checkBodies("PivotJointNew", a, b); err != nil {
+		return PivotJoint{}, err
+	}
+
+	return PivotJointNew(a, b, pivot), nil
+}
+
+// PivotJointNew2Checked is PivotJointNew2 returning an error for invalid bodies.
+func PivotJointNew2Checked(a, b Body, anchr1, anchr2 Vect) (PivotJoint, error) {
+	if err := checkBodies("PivotJointNew2", a, b); err != nil {
+		return PivotJoint{}, err
+	}
+
+	return PivotJointNew2(a, b, anchr1, anchr2), nil
+}
+
+// RatchetJointNewChecked is RatchetJointNew returning an error for invalid bodies
+// or a zero ratchet.
+func RatchetJointNewChecked(a, b Body, phase, ratchet float64) (RatchetJoint, error) {
+	const op = "RatchetJointNew"
+	if err := checkBodies(op, a, b); err != nil {
+		return RatchetJoint{}, err
+	}
+	if 0.0 == ratchet || math.IsNaN(ratchet) || math.IsInf(ratchet, 0) {
+		return RatchetJoint{}, checkError(op, fmt.Errorf("%w: ratchet %g must be finite and non-zero",
+			ErrInvalidArgument, ratchet))
+	}
+
+	return RatchetJointNew(a, b, phase, ratchet), nil
+}
+
+// RotaryLimitJointNewChecked is RotaryLimitJointNew returning an error for invalid
+// bodies or limits.
+func RotaryLimitJointNewChecked(a, b Body, min, max float64) (RotaryLimitJoint, error) {
+	const op = "RotaryLimitJointNew"
+	if err := checkBodies(op, a, b); err != nil {
+		return RotaryLimitJoint{}, err
+	}
+	if err := checkLimits(op, min, max); err != nil {
+		return RotaryLimitJoint{}, err
+	}
+
+	return RotaryLimitJointNew(a, b, min, max), nil
+}
+
+// SimpleMotorNewChecked is SimpleMotorNew returning an error for invalid bodies or rate.
+func SimpleMotorNewChecked(a, b Body, rate float64) (SimpleMotor, error) {
+	const op = "SimpleMotorNew"
+	if err := checkBodies(op, a, b); err != nil {
+		return SimpleMotor{}, err
+	}
+	if math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return SimpleMotor{}, checkError(op, fmt.Errorf("%w: rate %g must be finite", ErrInvalidArgument, rate))
+	}
+
+	return SimpleMotorNew(a, b, rate), nil
+}
+
+// SlideJointNewChecked is SlideJointNew returning an error for invalid bodies or limits.
+func SlideJointNewChecked(a, b Body, anchr1, anchr2 Vect, min, max float64) (SlideJoint, error) {
+	const op = "SlideJointNew"
+	if err := checkBodies(op, a, b); err != nil {
+		return SlideJoint{}, err
+	}
+	if err := checkNonNegative(op, "min", min); err != nil {
+		return SlideJoint{}, err
+	}
+	if err := checkLimits(op, min, max); err != nil {
+		return SlideJoint{}, err
+	}
+
+	return SlideJointNew(a, b, anchr1, anchr2, min, max), nil
+}
+
+// checkBodies checks the bodies of a constraint are set and distinct.
+func checkBodies(op string, a, b Body) error {
+	if 0 == a || 0 == b {
+		return checkError(op, fmt.Errorf("%w: constraint body", ErrNilObject))
+	}
+	if a == b {
+		return checkError(op, fmt.Errorf("%w: constraint connects a body to itself", ErrInvalidArgument))
+	}
+	return nil
+}
+
+// checkLimits checks min and max limits of a constraint.
+func checkLimits(op string, min, max float64) error {
+	if math.IsNaN(min) || math.IsNaN(max) || min > max {
+		return checkError(op, fmt.Errorf("%w: min %g must not be greater than max %g",
+			ErrInvalidArgument, min, max))
+	}
+	return nil
+}
+
+// checkSpring checks the bodies, stiffness and damping of a spring.
+func checkSpring(op string, a, b Body, stiffness, damping float64) error {
+	if err := checkBodies(op, a, b); err != nil {
+		return err
+	}
+	if err := checkNonNegative(op, "stiffness", stiffness); err != nil {
+		return err
+	}
+	return checkNonNegative(op, "damping", damping)
+}
@@ -0,0 +1,160 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"errors"
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_CheckedSpaceAdd(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)).SetCollisionType(1)
+
+	ballBody := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	ballBody.SetPosition(VectNew(0.0, 0.9))
+	ball := s.AddShape(CircleShapeNew(ballBody, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	cs := s.Checked()
+
+	err := cs.AddBody(ball.Body())
+	assert.T(t, errors.Is(err, ErrAlreadyInSpace), err)
+	assert.T(t, errors.Is(cs.Add(ball.(SpaceObject)), ErrAlreadyInSpace))
+	assert.T(t, errors.Is(cs.AddBody(s.StaticBody()), ErrStaticBody))
+	assert.T(t, errors.Is(cs.AddBody(0), ErrNilObject))
+
+	other := SpaceNew()
+	defer other.Free()
+	assert.T(t, errors.Is(other.Checked().AddShape(ball), ErrInOtherSpace))
+
+	b := BodyNew(1.0, 1.0)
+	s.EachBody(func(Body) {
+		err = cs.AddBody(b)
+	})
+	assert.T(t, errors.Is(err, ErrSpaceLocked), err)
+
+	assert.Equal(t, nil, cs.AddBody(b))
+	assert.Equal(t, s, b.Space())
+
+	s.Free()
+}
+
+func Test_CheckedSpaceRemove(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)).SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	cs := s.Checked()
+
+	sh := CircleShapeNew(ball.Body(), 0.5, Origin())
+	defer sh.Free()
+
+	err := cs.RemoveShape(sh)
+	assert.T(t, errors.Is(err, ErrNotInSpace), err)
+	assert.T(t, errors.Is(cs.RemoveBody(s.StaticBody()), ErrStaticBody))
+
+	assert.Equal(t, nil, cs.Remove(ball.(SpaceObject)))
+	assert.T(t, errors.Is(cs.Remove(ball.(SpaceObject)), ErrNotInSpace))
+
+	s.Free()
+}
+
+func Test_CheckedSpaceShapeGroup(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+	cs := s.Checked()
+
+	chain := SegmentChainNew(s.StaticBody(), []Vect{{0, 0}, {1, 0}, {1, 1}}, 0.0, false)
+	defer chain.Free()
+
+	// none of the segments is added if one of them can't be
+	s.AddShape(chain.Shapes[1])
+	err := cs.Add(chain)
+	assert.T(t, errors.Is(err, ErrAlreadyInSpace), err)
+	assert.T(t, !s.Contains(chain.Shapes[0]))
+
+	s.RemoveShape(chain.Shapes[1])
+	assert.Equal(t, nil, cs.Add(chain))
+	assert.T(t, s.Contains(chain))
+
+	assert.Equal(t, nil, cs.Remove(chain))
+	assert.T(t, !s.Contains(chain.Shapes[1]))
+	assert.T(t, errors.Is(cs.Remove(chain), ErrNotInSpace))
+}
+
+func Test_CheckedShapeNew(t *testing.T) {
+	b := BodyNew(1.0, 1.0)
+	defer b.Free()
+
+	cw := []Vect{{-1, -1}, {-1, 1}, {1, 1}, {1, -1}}
+	ccw := []Vect{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}
+	concave := []Vect{{-1, -1}, {-1, 1}, {0, 0}, {1, 1}, {1, -1}}
+
+	p, err := PolyShapeNewChecked(b, cw, Origin())
+	assert.Equal(t, nil, err)
+	p.Free()
+
+	for _, verts := range [][]Vect{ccw, concave, cw[:2], nil} {
+		_, err = PolyShapeNewChecked(b, verts, Origin())
+		assert.T(t, errors.Is(err, ErrInvalidArgument), err)
+	}
+
+	_, err = CircleShapeNewChecked(b, -1.0, Origin())
+	assert.T(t, errors.Is(err, ErrInvalidArgument), err)
+
+	_, err = BoxShapeNewChecked(b, 0.0, 1.0)
+	assert.T(t, errors.Is(err, ErrInvalidArgument), err)
+}
+
+func Test_CheckedConstraintNew(t *testing.T) {
+	a, b := BodyNew(1.0, 1.0), BodyNew(1.0, 1.0)
+	defer a.Free()
+	defer b.Free()
+
+	_, err := PivotJointNewChecked(a, a, Origin())
+	assert.T(t, errors.Is(err, ErrInvalidArgument), err)
+
+	_, err = PinJointNewChecked(a, 0, Origin(), Origin())
+	assert.T(t, errors.Is(err, ErrNilObject), err)
+
+	_, err = SlideJointNewChecked(a, b, Origin(), Origin(), 2.0, 1.0)
+	assert.T(t, errors.Is(err, ErrInvalidArgument), err)
+
+	_, err = GearJointNewChecked(a, b, 0.0, 0.0)
+	assert.T(t, errors.Is(err, ErrInvalidArgument), err)
+
+	j, err := PivotJointNewChecked(a, b, Origin())
+	assert.Equal(t, nil, err)
+	j.Free()
+}
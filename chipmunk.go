@@ -64,7 +64,7 @@ const (
 	// NoGroup is a value for Shape.Group signifying that a shape is in no group.
 	NoGroup = Group(0)
 	// AllLayers is a value for Shape.Layers signifying that a shape is in every layer.
-	AllLayers = Layers(0)
+	AllLayers = Layers(^uint32(0))
 )
 
 // Chipmunk version.
@@ -217,14 +217,3 @@ func (l Layers) c() C.cpLayers {
 func cpBool(b C.cpBool) bool {
 	return int(b) != 0
 }
-
-// cpData converts C.cpDataPointer to interface.
-func cpData(p C.cpDataPointer) interface{} {
-	data := *(*interface{})(p)
-	return data
-}
-
-// dataToC converts interface to C.cpDataPointer.
-func dataToC(data interface{}) C.cpDataPointer {
-	return C.cpDataPointer(unsafe.Pointer(&data))
-}
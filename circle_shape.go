@@ -53,6 +53,13 @@ func CircleShapeNew(body Body, radius float64, offset Vect) CircleShape {
 	return CircleShape{cpshape(s)}
 }
 
+// MassProperties returns the mass properties of the circle for a density.
+func (s CircleShape) MassProperties(density float64) MassProperties {
+	r := s.Radius()
+	m := density * AreaForCircle(0.0, r)
+	return MassProperties{m, MomentForCircle(m, 0.0, r, Origin()), s.Offset()}
+}
+
 // Offset returns the offset from the center of gravity.
 func (s CircleShape) Offset() Vect {
 	return cpVect(C.cpCircleShapeGetOffset(s.c()))
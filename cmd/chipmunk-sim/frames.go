@@ -0,0 +1,126 @@
+package main
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/ianremmler/chipmunk"
+	"github.com/ianremmler/chipmunk/debugdraw"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// frameWriter writes pictures of a space into a directory.
+type frameWriter struct {
+	dir           string
+	format        string
+	bb            chipmunk.BB
+	width, height int
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+var frameBackground = color.NRGBA{25, 25, 30, 255}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// newFrameWriter creates a frame writer and its directory. Unless given by
+// the options, the area shown is the one taken by the shapes of the space.
+func newFrameWriter(s chipmunk.Space, o *options) (*frameWriter, error) {
+	fw := &frameWriter{dir: o.frames, format: o.frameFormat, width: o.width, height: o.height}
+
+	if "" != o.bounds {
+		var l, b, r, t float64
+		if _, err := fmt.Sscanf(o.bounds, "%g,%g,%g,%g", &l, &b, &r, &t); err != nil || !(l < r && b < t) {
+			return nil, fmt.Errorf("invalid bounds %q", o.bounds)
+		}
+		fw.bb = chipmunk.BBNew(l, b, r, t)
+	} else {
+		fw.bb = sceneBB(s)
+	}
+
+	if err := os.MkdirAll(o.frames, 0755); err != nil {
+		return nil, err
+	}
+
+	return fw, nil
+}
+
+// sceneBB returns the bounding box of all shapes in a space with a margin around.
+func sceneBB(s chipmunk.Space) chipmunk.BB {
+	bb := chipmunk.BBNew(-1.0, -1.0, 1.0, 1.0)
+	first := true
+
+	s.EachShape(func(sh chipmunk.Shape) {
+		if first {
+			bb, first = sh.BB(), false
+		} else {
+			bb = bb.Merge(sh.BB())
+		}
+	})
+
+	m := 0.05 * (bb.Right() - bb.Left() + bb.Top() - bb.Bottom())
+	if 0.0 == m {
+		m = 1.0
+	}
+
+	return chipmunk.BBNew(bb.Left()-m, bb.Bottom()-m, bb.Right()+m, bb.Top()+m)
+}
+
+// Write writes a picture of a space at a step.
+func (fw *frameWriter) Write(s chipmunk.Space, step int) (err error) {
+	f, err := os.Create(filepath.Join(fw.dir, fmt.Sprintf("frame-%06d.%s", step, fw.format)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); nil == err {
+			err = cerr
+		}
+	}()
+
+	if "svg" == fw.format {
+		w := bufio.NewWriter(f)
+		d := debugdraw.NewSVG(w, fw.bb, fw.width, fw.height)
+		chipmunk.DebugDraw(s, d)
+		if err := d.Close(); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, fw.width, fw.height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(frameBackground), image.Point{}, draw.Src)
+	chipmunk.DebugDraw(s, debugdraw.NewImage(img, fw.bb))
+
+	return png.Encode(f, img)
+}
@@ -0,0 +1,246 @@
+/*
+Command chipmunk-sim steps a scene without a display and writes body trajectories.
+
+Usage:
+
+	chipmunk-sim [flags] scene.json
+
+The scene is a JSON document as read by package encoding; "-" reads it from
+the standard input. Each recorded step writes one row per body: the step
+number, the simulated time, the body index (bodies are numbered in the order
+of the space at load time), position, angle, velocity and angular velocity.
+
+With -frames, pictures of the space are written to a directory as
+frame-NNNNNN.png or frame-NNNNNN.svg every -frame-every steps.
+*/
+package main
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ianremmler/chipmunk"
+	"github.com/ianremmler/chipmunk/encoding"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// options holds the command line flags.
+type options struct {
+	steps       int
+	dt          float64
+	format      string
+	output      string
+	every       int
+	frames      string
+	frameEvery  int
+	frameFormat string
+	width       int
+	height      int
+	bounds      string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "chipmunk-sim:", err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses and checks the command line.
+func parseFlags(args []string, stderr io.Writer) (*options, string, error) {
+	var o options
+
+	fs := flag.NewFlagSet("chipmunk-sim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: chipmunk-sim [flags] scene.json")
+		fs.PrintDefaults()
+	}
+
+	fs.IntVar(&o.steps, "steps", 600, "number of steps")
+	fs.Float64Var(&o.dt, "dt", 1.0/60.0, "time step")
+	fs.StringVar(&o.format, "format", "csv", "trajectory format: csv or jsonl")
+	fs.StringVar(&o.output, "o", "", "trajectory file (standard output by default)")
+	fs.IntVar(&o.every, "every", 1, "record trajectories every n steps")
+	fs.StringVar(&o.frames, "frames", "", "directory to write frames to")
+	fs.IntVar(&o.frameEvery, "frame-every", 60, "write a frame every n steps")
+	fs.StringVar(&o.frameFormat, "frame-format", "png", "frame format: png or svg")
+	fs.IntVar(&o.width, "width", 640, "frame width in pixels")
+	fs.IntVar(&o.height, "height", 480, "frame height in pixels")
+	fs.StringVar(&o.bounds, "bounds", "", "world area shown in frames as l,b,r,t (fits all shapes by default)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+
+	if 1 != fs.NArg() {
+		fs.Usage()
+		return nil, "", fmt.Errorf("expected one scene file, got %d arguments", fs.NArg())
+	}
+
+	switch {
+	case o.steps < 0:
+		return nil, "", fmt.Errorf("invalid number of steps %d", o.steps)
+	case !(o.dt > 0.0):
+		return nil, "", fmt.Errorf("invalid time step %g", o.dt)
+	case o.every < 1:
+		return nil, "", fmt.Errorf("invalid record interval %d", o.every)
+	case o.frameEvery < 1:
+		return nil, "", fmt.Errorf("invalid frame interval %d", o.frameEvery)
+	case o.width < 1 || o.height < 1:
+		return nil, "", fmt.Errorf("invalid frame size %dx%d", o.width, o.height)
+	case "png" != o.frameFormat && "svg" != o.frameFormat:
+		return nil, "", fmt.Errorf("unknown frame format %q", o.frameFormat)
+	}
+
+	return &o, fs.Arg(0), nil
+}
+
+// loadScene reads a space from a scene file ("-" is the standard input).
+func loadScene(name string, stdin io.Reader) (chipmunk.Space, error) {
+	r := stdin
+
+	if "-" != name {
+		f, err := os.Open(name)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	s, err := encoding.NewDecoder(r).Decode()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return s, nil
+}
+
+// run is the whole command, apart from the process exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) (err error) {
+	o, scene, err := parseFlags(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	out := stdout
+	if "" != o.output {
+		f, ferr := os.Create(o.output)
+		if ferr != nil {
+			return ferr
+		}
+		defer func() {
+			if cerr := f.Close(); nil == err {
+				err = cerr
+			}
+		}()
+		out = f
+	}
+
+	tw, err := newTrajectoryWriter(o.format, out)
+	if err != nil {
+		return err
+	}
+
+	s, err := loadScene(scene, stdin)
+	if err != nil {
+		return err
+	}
+	defer freeSpace(s)
+
+	var fw *frameWriter
+	if "" != o.frames {
+		if fw, err = newFrameWriter(s, o); err != nil {
+			return err
+		}
+	}
+
+	bodies := spaceBodies(s)
+
+	for step := 0; ; step++ {
+		if 0 == step%o.every {
+			if err := tw.Write(step, float64(step)*o.dt, bodies); err != nil {
+				return err
+			}
+		}
+
+		if nil != fw && 0 == step%o.frameEvery {
+			if err := fw.Write(s, step); err != nil {
+				return err
+			}
+		}
+
+		if step == o.steps {
+			break
+		}
+
+		s.Step(o.dt)
+	}
+
+	return tw.Flush()
+}
+
+// freeSpace frees a space and all its objects.
+func freeSpace(s chipmunk.Space) {
+	var bodies []chipmunk.Body
+	var shapes []chipmunk.Shape
+	var constraints []chipmunk.Constraint
+
+	s.EachBody(func(b chipmunk.Body) { bodies = append(bodies, b) })
+	s.EachShape(func(sh chipmunk.Shape) { shapes = append(shapes, sh) })
+	s.EachConstraint(func(c chipmunk.Constraint) { constraints = append(constraints, c) })
+
+	s.Free()
+
+	for _, c := range constraints {
+		c.Free()
+	}
+	for _, sh := range shapes {
+		sh.Free()
+	}
+	for _, b := range bodies {
+		b.Free()
+	}
+}
+
+// spaceBodies returns the bodies of a space. Their indices are the body numbers
+// in trajectories, so they must be taken once, as the iteration order of a space
+// changes when bodies fall asleep.
+func spaceBodies(s chipmunk.Space) []chipmunk.Body {
+	var bodies []chipmunk.Body
+
+	s.EachBody(func(b chipmunk.Body) {
+		bodies = append(bodies, b)
+	})
+
+	return bodies
+}
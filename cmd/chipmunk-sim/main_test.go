@@ -0,0 +1,143 @@
+package main
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/ianremmler/chipmunk"
+	"github.com/ianremmler/chipmunk/encoding"
+)
+
+// sceneFile writes a scene with a ball falling onto the ground and returns its name.
+func sceneFile(t *testing.T) string {
+	s := chipmunk.SpaceNew()
+	s.SetGravity(chipmunk.VectNew(0.0, -100.0))
+	s.AddShape(chipmunk.SegmentShapeNew(s.StaticBody(), chipmunk.VectNew(-10, 0), chipmunk.VectNew(10, 0), 0.0))
+
+	b := s.AddBody(chipmunk.BodyNew(1.0, chipmunk.MomentForCircle(1.0, 0.0, 1.0, chipmunk.Origin())))
+	b.SetPosition(chipmunk.VectNew(0.0, 5.0))
+	s.AddShape(chipmunk.CircleShapeNew(b, 1.0, chipmunk.Origin()))
+
+	var buf bytes.Buffer
+	assert.Equal(t, nil, encoding.NewEncoder(&buf).Encode(s))
+	freeSpace(s)
+
+	name := filepath.Join(t.TempDir(), "scene.json")
+	assert.Equal(t, nil, ioutil.WriteFile(name, buf.Bytes(), 0644))
+
+	return name
+}
+
+func Test_RunCSV(t *testing.T) {
+	var out bytes.Buffer
+
+	err := run([]string{"-steps", "10", "-every", "5", sceneFile(t)}, nil, &out, ioutil.Discard)
+	assert.Equal(t, nil, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, "step,time,body,x,y,angle,vx,vy,w", lines[0])
+	assert.T(t, strings.HasPrefix(lines[1], "0,0,0,0,5,0,0,0,0"))
+	assert.T(t, strings.HasPrefix(lines[3], "10,"))
+}
+
+func Test_RunJSONLStdin(t *testing.T) {
+	scene, err := os.Open(sceneFile(t))
+	assert.Equal(t, nil, err)
+	defer scene.Close()
+
+	var out bytes.Buffer
+
+	err = run([]string{"-format", "jsonl", "-dt", "0.03125", "-steps", "8", "-every", "8", "-"}, scene, &out, ioutil.Discard)
+	assert.Equal(t, nil, err)
+
+	dec := json.NewDecoder(&out)
+	var first, last record
+	assert.Equal(t, nil, dec.Decode(&first))
+	assert.Equal(t, nil, dec.Decode(&last))
+	assert.T(t, !dec.More())
+
+	assert.Equal(t, 8, last.Step)
+	assert.Equal(t, jsonFloat(0.25), last.Time)
+	assert.T(t, last.Y < first.Y)
+	assert.T(t, last.VY < 0.0)
+}
+
+func Test_RunFrames(t *testing.T) {
+	for _, format := range []string{"png", "svg"} {
+		dir := filepath.Join(t.TempDir(), "frames")
+
+		err := run([]string{
+			"-steps", "10", "-frames", dir, "-frame-every", "5", "-frame-format", format,
+			"-width", "64", "-height", "48", "-o", filepath.Join(t.TempDir(), "out.csv"), sceneFile(t),
+		}, nil, ioutil.Discard, ioutil.Discard)
+		assert.Equal(t, nil, err)
+
+		for _, name := range []string{"frame-000000.", "frame-000005.", "frame-000010."} {
+			fi, err := os.Stat(filepath.Join(dir, name+format))
+			assert.Equal(t, nil, err)
+			assert.T(t, fi.Size() > 0)
+		}
+	}
+}
+
+func Test_RunInvalid(t *testing.T) {
+	scene := sceneFile(t)
+
+	for _, args := range [][]string{
+		{},
+		{scene, scene},
+		{"-dt", "0", scene},
+		{"-steps", "-1", scene},
+		{"-format", "xml", scene},
+		{"-frame-format", "gif", "-frames", t.TempDir(), scene},
+		{"-bounds", "1,1,0,0", "-frames", t.TempDir(), scene},
+		{filepath.Join(t.TempDir(), "missing.json")},
+	} {
+		assert.NotEqual(t, nil, run(args, nil, ioutil.Discard, ioutil.Discard), args)
+	}
+}
+
+func Test_RecordJSONNonFinite(t *testing.T) {
+	r := record{X: jsonFloat(math.NaN()), Y: jsonFloat(math.Inf(1)), VY: jsonFloat(math.Inf(-1)), W: 0.5}
+
+	data, err := json.Marshal(r)
+	assert.Equal(t, nil, err)
+
+	var m map[string]interface{}
+	assert.Equal(t, nil, json.Unmarshal(data, &m))
+	assert.Equal(t, "NaN", m["x"])
+	assert.Equal(t, "Infinity", m["y"])
+	assert.Equal(t, "-Infinity", m["vy"])
+	assert.Equal(t, 0.5, m["w"])
+}
@@ -0,0 +1,167 @@
+package main
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/ianremmler/chipmunk"
+	"github.com/ianremmler/chipmunk/encoding"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// trajectoryWriter writes the state of bodies at a step.
+type trajectoryWriter interface {
+	Write(step int, time float64, bodies []chipmunk.Body) error
+	Flush() error
+}
+
+// jsonFloat is a float64 which encodes NaN as a "NaN" string and infinities as
+// encoding.Float does, so that an exploding body doesn't abort the run.
+type jsonFloat float64
+
+// record is the state of a body at a step.
+type record struct {
+	Step  int       `json:"step"`
+	Time  jsonFloat `json:"time"`
+	Body  int       `json:"body"`
+	X     jsonFloat `json:"x"`
+	Y     jsonFloat `json:"y"`
+	Angle jsonFloat `json:"angle"`
+	VX    jsonFloat `json:"vx"`
+	VY    jsonFloat `json:"vy"`
+	W     jsonFloat `json:"w"`
+}
+
+// csvWriter writes records as CSV with a header line.
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// jsonlWriter writes records as JSON Lines.
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+var csvHeader = []string{"step", "time", "body", "x", "y", "angle", "vx", "vy", "w"}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// MarshalJSON implements json.Marshaler.
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(f)) {
+		return []byte(`"NaN"`), nil
+	}
+	return encoding.Float(f).MarshalJSON()
+}
+
+// newRecord returns the state of a body.
+func newRecord(step int, time float64, i int, b chipmunk.Body) record {
+	p, v := b.Position(), b.Velocity()
+
+	return record{
+		Step:  step,
+		Time:  jsonFloat(time),
+		Body:  i,
+		X:     jsonFloat(p.X),
+		Y:     jsonFloat(p.Y),
+		Angle: jsonFloat(b.Angle()),
+		VX:    jsonFloat(v.X),
+		VY:    jsonFloat(v.Y),
+		W:     jsonFloat(b.AngularVelocity()),
+	}
+}
+
+// newTrajectoryWriter returns a writer of specific format (csv or jsonl).
+func newTrajectoryWriter(format string, w io.Writer) (trajectoryWriter, error) {
+	switch format {
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "jsonl":
+		bw := bufio.NewWriter(w)
+		return &jsonlWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown trajectory format %q", format)
+}
+
+// Flush implements trajectoryWriter.
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Write implements trajectoryWriter.
+func (w *csvWriter) Write(step int, time float64, bodies []chipmunk.Body) error {
+	if !w.header {
+		w.header = true
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	f := func(v jsonFloat) string {
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	}
+
+	for i, b := range bodies {
+		r := newRecord(step, time, i, b)
+		row := []string{
+			strconv.Itoa(r.Step), f(r.Time), strconv.Itoa(r.Body),
+			f(r.X), f(r.Y), f(r.Angle), f(r.VX), f(r.VY), f(r.W),
+		}
+		if err := w.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush implements trajectoryWriter.
+func (w *jsonlWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Write implements trajectoryWriter.
+func (w *jsonlWriter) Write(step int, time float64, bodies []chipmunk.Body) error {
+	for i, b := range bodies {
+		if err := w.enc.Encode(newRecord(step, time, i, b)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"sync/atomic"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CollisionEventKind is a kind of collision event. Kinds are bit flags, so they may
+// be combined in a CollisionEventFilter.
+type CollisionEventKind uint
+
+// Collision event kinds.
+const (
+	// CollisionBegin is recorded when two shapes start touching.
+	CollisionBegin CollisionEventKind = 1 << iota
+	// CollisionPostSolve is recorded each step while two shapes are touching,
+	// after the collision is solved.
+	CollisionPostSolve
+	// CollisionSeparate is recorded when two shapes stop touching.
+	CollisionSeparate
+)
+
+// CollisionEvent is a record of a collision callback. Unlike an Arbiter,
+// it stays valid after the step, but its shapes and bodies may be freed by then.
+type CollisionEvent struct {
+	Kind CollisionEventKind
+	// ShapeA and ShapeB are in the order of the collision types of the filter.
+	ShapeA, ShapeB Shape
+	BodyA, BodyB   Body
+	Points         []ContactPoint
+	// Impulse and KE are the total impulse applied and the energy lost.
+	// They're only set for CollisionPostSolve events.
+	Impulse Vect
+	KE      float64
+}
+
+// CollisionEventFilter selects collision events recorded by a stream.
+type CollisionEventFilter struct {
+	// A and B are the collision types of the shapes, in either order.
+	// AnyCollisionType matches every type.
+	A, B CollisionType
+	// Kinds is a set of event kinds. Zero means all kinds.
+	Kinds CollisionEventKind
+}
+
+// CollisionEventStream delivers collision events recorded during Space.Step
+// once the step is done. Events which don't fit the channel buffer are dropped.
+type CollisionEventStream struct {
+	// C is the channel on which the events are delivered.
+	// It's closed by Close and when the space is freed.
+	C <-chan CollisionEvent
+
+	c       chan CollisionEvent
+	space   Space
+	filter  CollisionEventFilter
+	pending []CollisionEvent
+	dropped uint64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CollisionEvents creates a stream of collision events matching a filter,
+// with a channel buffer of specific size.
+func (s Space) CollisionEvents(filter CollisionEventFilter, size int) *CollisionEventStream {
+	c := make(chan CollisionEvent, size)
+	st := &CollisionEventStream{C: c, c: c, space: s, filter: filter}
+
+	d := s.data()
+	d.mu.Lock()
+	d.collisionEventStreams = append(d.collisionEventStreams, st)
+	d.mu.Unlock()
+
+	s.syncCollisionHandler(collisionKey(AnyCollisionType, AnyCollisionType))
+
+	return st
+}
+
+// Close stops recording events and closes the channel.
+// Events not delivered yet are discarded.
+func (st *CollisionEventStream) Close() {
+	d := st.space.data()
+	if nil == d {
+		// the space was freed
+		return
+	}
+
+	d.mu.Lock()
+	found := false
+	for i, other := range d.collisionEventStreams {
+		if other == st {
+			d.collisionEventStreams = append(d.collisionEventStreams[:i], d.collisionEventStreams[i+1:]...)
+			close(st.c)
+			found = true
+			break
+		}
+	}
+	d.mu.Unlock()
+
+	if found {
+		st.space.syncCollisionHandler(collisionKey(AnyCollisionType, AnyCollisionType))
+	}
+}
+
+// Dropped returns the number of events dropped because the channel was full.
+func (st *CollisionEventStream) Dropped() uint64 {
+	return atomic.LoadUint64(&st.dropped)
+}
+
+// closeCollisionEventStreams closes the channels of all streams of a freed space.
+// The caller must hold the space data mutex.
+func (d *spaceData) closeCollisionEventStreams() {
+	for _, st := range d.collisionEventStreams {
+		close(st.c)
+	}
+	d.collisionEventStreams = nil
+}
+
+// flushCollisionEvents delivers the events recorded during a step.
+func (s Space) flushCollisionEvents() {
+	d := s.data()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, st := range d.collisionEventStreams {
+		for _, e := range st.pending {
+			select {
+			case st.c <- e:
+			default:
+				atomic.AddUint64(&st.dropped, 1)
+			}
+		}
+
+		for i := range st.pending {
+			st.pending[i] = CollisionEvent{}
+		}
+		st.pending = st.pending[:0]
+	}
+}
+
+// match returns true if an event of specific kind between shapes of specific
+// collision types passes the filter, and whether the shapes must be swapped
+// to be in the order of the filter.
+func (f CollisionEventFilter) match(kind CollisionEventKind, ta, tb CollisionType) (bool, bool) {
+	if 0 != f.Kinds && 0 == f.Kinds&kind {
+		return false, false
+	}
+
+	m := func(t, other CollisionType) bool {
+		return t == AnyCollisionType || t == other
+	}
+
+	switch {
+	case m(f.A, ta) && m(f.B, tb):
+		return true, false
+	case m(f.A, tb) && m(f.B, ta):
+		return true, true
+	}
+
+	return false, false
+}
+
+// newCollisionEvent reads an event from an arbiter.
+func newCollisionEvent(kind CollisionEventKind, arb Arbiter) CollisionEvent {
+	e := CollisionEvent{Kind: kind, Points: arb.ContactPoints()}
+	e.ShapeA, e.ShapeB = arb.Shapes()
+	e.BodyA, e.BodyB = arb.Bodies()
+
+	if CollisionPostSolve == kind {
+		e.Impulse = arb.TotalImpulse()
+		e.KE = arb.TotalKE()
+	}
+
+	return e
+}
+
+// recordCollisionEvent records an event for every stream of the space it matches.
+func (s Space) recordCollisionEvent(kind CollisionEventKind, arb Arbiter) {
+	d := s.data()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if 0 == len(d.collisionEventStreams) {
+		return
+	}
+
+	sa, sb := arb.Shapes()
+	ta, tb := sa.CollisionType(), sb.CollisionType()
+
+	for _, st := range d.collisionEventStreams {
+		ok, swap := st.filter.match(kind, ta, tb)
+		if !ok {
+			continue
+		}
+
+		if swap {
+			arb.swap()
+		}
+		st.pending = append(st.pending, newCollisionEvent(kind, arb))
+		if swap {
+			arb.swap()
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_CollisionEvents(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	ground.SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	st := s.CollisionEvents(CollisionEventFilter{A: 2, B: 1}, 64)
+
+	inStep := -1
+	s.SetCollisionHandler(1, 2, CollisionHandlerFuncs{
+		PreSolveFunc: func(s Space, arb Arbiter) bool {
+			inStep = len(st.C)
+			return true
+		},
+	})
+
+	s.Step(1.0 / 60.0)
+	assert.Equal(t, 0, inStep)
+
+	e := <-st.C
+	assert.Equal(t, CollisionBegin, e.Kind)
+	assert.Equal(t, ball, e.ShapeA)
+	assert.Equal(t, ground, e.ShapeB)
+	assert.Equal(t, ball.Body(), e.BodyA)
+	assert.Equal(t, s.StaticBody(), e.BodyB)
+	assert.NotEqual(t, 0, len(e.Points))
+
+	e = <-st.C
+	assert.Equal(t, CollisionPostSolve, e.Kind)
+	assert.T(t, e.Impulse.Length() > 0.0)
+	assert.Equal(t, 0, len(st.C))
+
+	ball.Body().SetPosition(VectNew(0.0, 10.0))
+	s.Step(1.0 / 60.0)
+
+	e = <-st.C
+	assert.Equal(t, CollisionSeparate, e.Kind)
+	assert.Equal(t, ball, e.ShapeA)
+
+	st.Close()
+	_, ok := <-st.C
+	assert.T(t, !ok)
+
+	s.Free()
+}
+
+func Test_CollisionEventsFilter(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)).SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	s.AddShape(CircleShapeNew(b, 1.0, Origin())).SetCollisionType(2)
+
+	begins := s.CollisionEvents(CollisionEventFilter{A: AnyCollisionType, B: AnyCollisionType, Kinds: CollisionBegin}, 64)
+	other := s.CollisionEvents(CollisionEventFilter{A: 1, B: 3}, 64)
+	small := s.CollisionEvents(CollisionEventFilter{A: 1, B: AnyCollisionType, Kinds: CollisionPostSolve}, 1)
+
+	for i := 0; i < 3; i++ {
+		s.Step(1.0 / 60.0)
+	}
+
+	assert.Equal(t, 1, len(begins.C))
+	assert.Equal(t, 0, len(other.C))
+	assert.Equal(t, 1, len(small.C))
+	assert.Equal(t, uint64(2), small.Dropped())
+
+	s.Free()
+
+	_, ok := <-other.C
+	assert.T(t, !ok)
+	other.Close()
+}
@@ -0,0 +1,489 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// #include <chipmunk/chipmunk.h>
+// #include "space.h"
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+
+// AnyCollisionType matches every collision type in SetCollisionHandler.
+const AnyCollisionType = ^CollisionType(0)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CollisionFilter decides whether collisions between shapes of specific collision
+// types are processed. Unlike a collision handler, any number of filters may be
+// added for a pair of collision types, they are consulted before the handler.
+// Within its methods the arbiter reports shapes and bodies in the order of the
+// collision types the filter was added for.
+//
+// A filter may also have the Begin method of CollisionHandler, to reject
+// collisions when the shapes start touching, and the Separate method, to be told
+// when they stop touching. Separate is called for rejected collisions too.
+type CollisionFilter interface {
+	// PreSolve is called each step while the shapes are touching, before the
+	// collision handler. Returning false ignores the collision for this step and
+	// skips the remaining filters and the handler.
+	PreSolve(Space, Arbiter) bool
+}
+
+// CollisionHandler handles collisions between shapes of specific collision types.
+// Within its methods the arbiter reports shapes and bodies in the order of the
+// collision types the handler was set for.
+type CollisionHandler interface {
+	// Begin is called when two shapes just started touching for the first time this step.
+	// Returning false makes the collision ignored until they separate.
+	Begin(Space, Arbiter) bool
+	// PreSolve is called each step while the shapes are touching, before the collision is solved.
+	// Returning false ignores the collision for this step.
+	PreSolve(Space, Arbiter) bool
+	// PostSolve is called each step while the shapes are touching, after the collision is solved.
+	PostSolve(Space, Arbiter)
+	// Separate is called when the shapes stopped touching for the first time this step.
+	Separate(Space, Arbiter)
+}
+
+// CollisionHandlerFuncs is a CollisionHandler made of functions.
+// Nil functions leave collisions to be processed as usual.
+type CollisionHandlerFuncs struct {
+	BeginFunc     func(Space, Arbiter) bool
+	PreSolveFunc  func(Space, Arbiter) bool
+	PostSolveFunc func(Space, Arbiter)
+	SeparateFunc  func(Space, Arbiter)
+}
+
+// collisionHandler is a CollisionHandler made of functions taking user data,
+// as set by AddCollisionHandler and SetDefaultCollisionHandler.
+type collisionHandler struct {
+	beginFunc    func(Space, Arbiter, interface{}) bool
+	preSolveFunc func(Space, Arbiter, interface{}) bool
+	postStepFunc func(Space, Arbiter, interface{})
+	separateFunc func(Space, Arbiter, interface{})
+	data         interface{}
+}
+
+// collisionBeginFilter is a collision filter with a Begin method.
+type collisionBeginFilter interface {
+	Begin(Space, Arbiter) bool
+}
+
+// collisionFilterEntry is a collision filter and the order of collision types it was added for.
+type collisionFilterEntry struct {
+	a, b   CollisionType
+	filter CollisionFilter
+}
+
+// collisionHandlerEntry is a collision handler and the order of collision types it was set for.
+type collisionHandlerEntry struct {
+	a, b    CollisionType
+	handler CollisionHandler
+}
+
+// collisionSeparateFilter is a collision filter with a Separate method.
+type collisionSeparateFilter interface {
+	Separate(Space, Arbiter)
+}
+
+// collisionHandlerSync is a post-step callback key for syncCollisionHandler.
+type collisionHandlerSync struct {
+	key collisionTypePair
+}
+
+// collisionTypePair is a pair of collision types, the lower one first.
+type collisionTypePair struct {
+	a, b CollisionType
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// AddCollisionFilter adds a collision filter for shapes of two collision types,
+// AnyCollisionType matching every type. Filters are kept apart from collision
+// handlers, so adding one doesn't replace the handler set for the pair.
+// The filter must be comparable (e.g. a pointer) to be removed.
+//
+// Filters may be added and removed while the space is locked. Adding a filter
+// for a pair of types which already has one or removing one is seen by the next
+// collision callback, but the first filter for a pair may only be called from
+// the next step on, as Chipmunk's handlers can't be changed while the space is
+// locked.
+func (s Space) AddCollisionFilter(a, b CollisionType, f CollisionFilter) {
+	key := collisionKey(a, b)
+
+	d := s.data()
+	d.mu.Lock()
+	d.collisionFilters[key] = append(d.collisionFilters[key], collisionFilterEntry{a, b, f})
+	added := 1 == len(d.collisionFilters[key])
+	d.mu.Unlock()
+
+	if added {
+		s.syncCollisionHandler(key)
+	}
+}
+
+// Begin implements CollisionHandler.
+func (h CollisionHandlerFuncs) Begin(s Space, arb Arbiter) bool {
+	if nil == h.BeginFunc {
+		return true
+	}
+	return h.BeginFunc(s, arb)
+}
+
+// PostSolve implements CollisionHandler.
+func (h CollisionHandlerFuncs) PostSolve(s Space, arb Arbiter) {
+	if nil != h.PostSolveFunc {
+		h.PostSolveFunc(s, arb)
+	}
+}
+
+// PreSolve implements CollisionHandler.
+func (h CollisionHandlerFuncs) PreSolve(s Space, arb Arbiter) bool {
+	if nil == h.PreSolveFunc {
+		return true
+	}
+	return h.PreSolveFunc(s, arb)
+}
+
+// Separate implements CollisionHandler.
+func (h CollisionHandlerFuncs) Separate(s Space, arb Arbiter) {
+	if nil != h.SeparateFunc {
+		h.SeparateFunc(s, arb)
+	}
+}
+
+// RemoveCollisionFilter removes a collision filter added for a pair of collision
+// types in the same order.
+func (s Space) RemoveCollisionFilter(a, b CollisionType, f CollisionFilter) {
+	key := collisionKey(a, b)
+
+	d := s.data()
+	d.mu.Lock()
+	filters := d.collisionFilters[key]
+	for i, e := range filters {
+		if e.a == a && e.b == b && e.filter == f {
+			filters = append(filters[:i:i], filters[i+1:]...)
+			break
+		}
+	}
+
+	removed := len(filters) < len(d.collisionFilters[key])
+	if 0 == len(filters) {
+		delete(d.collisionFilters, key)
+	} else {
+		d.collisionFilters[key] = filters
+	}
+	d.mu.Unlock()
+
+	if removed && 0 == len(filters) {
+		s.syncCollisionHandler(key)
+	}
+}
+
+// SetCollisionHandler sets a collision handler to be used whenever shapes of two
+// collision types collide, replacing the one set for the pair in either order.
+// A nil handler removes it.
+//
+// AnyCollisionType as one of the types sets a wildcard handler for collisions of
+// the other type with shapes of any type which have no handler of their own,
+// and as both types sets the default handler for all remaining collisions.
+// If both colliding types have wildcard handlers, the one of the lower type is used.
+//
+// Handlers may be set and removed while the space is locked, e.g. from within
+// a collision handler. Replacing or removing a handler is seen by the next
+// collision callback, but a handler for a pair of types which had none may only
+// be called from the next step on, as Chipmunk's handlers can't be changed
+// while the space is locked.
+func (s Space) SetCollisionHandler(a, b CollisionType, h CollisionHandler) {
+	if nil == h {
+		s.RemoveCollisionHandler(a, b)
+		return
+	}
+
+	key := collisionKey(a, b)
+
+	d := s.data()
+	d.mu.Lock()
+	_, replaced := d.collisionHandlers[key]
+	d.collisionHandlers[key] = collisionHandlerEntry{a, b, h}
+	d.mu.Unlock()
+
+	if !replaced {
+		s.syncCollisionHandler(key)
+	}
+}
+
+// Begin implements CollisionHandler.
+func (h collisionHandler) Begin(s Space, arb Arbiter) bool {
+	if nil == h.beginFunc {
+		return true
+	}
+	return h.beginFunc(s, arb, h.data)
+}
+
+// PostSolve implements CollisionHandler.
+func (h collisionHandler) PostSolve(s Space, arb Arbiter) {
+	if nil != h.postStepFunc {
+		h.postStepFunc(s, arb, h.data)
+	}
+}
+
+// PreSolve implements CollisionHandler.
+func (h collisionHandler) PreSolve(s Space, arb Arbiter) bool {
+	if nil == h.preSolveFunc {
+		return true
+	}
+	return h.preSolveFunc(s, arb, h.data)
+}
+
+// Separate implements CollisionHandler.
+func (h collisionHandler) Separate(s Space, arb Arbiter) {
+	if nil != h.separateFunc {
+		h.separateFunc(s, arb, h.data)
+	}
+}
+
+//export begin
+func begin(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) C.cpBool {
+	space, arb := cpSpace(s), cpArbiter(a)
+	space.recordCollisionEvent(CollisionBegin, arb)
+
+	for _, e := range space.findCollisionFilters(arb) {
+		if f, ok := e.filter.(collisionBeginFilter); ok {
+			if !inFilterOrder(arb, e, func() bool { return f.Begin(space, arb) }) {
+				return boolToC(false)
+			}
+		}
+	}
+
+	h, swap := space.findCollisionHandler(arb)
+	if nil == h {
+		return boolToC(true)
+	}
+	if swap {
+		arb.swap()
+		defer arb.swap()
+	}
+
+	return boolToC(h.Begin(space, arb))
+}
+
+// collisionSwapped returns whether shapes of collision types ta and tb must be
+// swapped to match the order a and b a handler or a filter was set for.
+func collisionSwapped(a, b, ta, tb CollisionType) bool {
+	match := func(t, other CollisionType) bool {
+		return t == AnyCollisionType || t == other
+	}
+	return !(match(a, ta) && match(b, tb))
+}
+
+// collisionKey returns the key of a pair of collision types in either order.
+func collisionKey(a, b CollisionType) collisionTypePair {
+	if a > b {
+		a, b = b, a
+	}
+	return collisionTypePair{a, b}
+}
+
+// findCollisionHandler returns the handler for the shapes of an arbiter and whether
+// the arbiter must be swapped to report them in the order of the handler.
+// It returns nil if there is no handler.
+func (s Space) findCollisionHandler(arb Arbiter) (CollisionHandler, bool) {
+	sa, sb := arb.Shapes()
+	ta, tb := sa.CollisionType(), sb.CollisionType()
+
+	d := s.data()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.collisionHandlers[collisionKey(ta, tb)]
+	if !ok {
+		wa, okA := d.collisionHandlers[collisionKey(ta, AnyCollisionType)]
+		wb, okB := d.collisionHandlers[collisionKey(tb, AnyCollisionType)]
+
+		switch {
+		case okA && (!okB || ta <= tb):
+			e, ok = wa, true
+		case okB:
+			e, ok = wb, true
+		default:
+			e, ok = d.collisionHandlers[collisionKey(AnyCollisionType, AnyCollisionType)]
+		}
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	return e.handler, collisionSwapped(e.a, e.b, ta, tb)
+}
+
+// findCollisionFilters returns the filters for the shapes of an arbiter: those
+// added for their collision types, then the wildcard ones.
+func (s Space) findCollisionFilters(arb Arbiter) []collisionFilterEntry {
+	sa, sb := arb.Shapes()
+	ta, tb := sa.CollisionType(), sb.CollisionType()
+
+	keys := []collisionTypePair{collisionKey(ta, tb), collisionKey(ta, AnyCollisionType)}
+	if ta != tb {
+		keys = append(keys, collisionKey(tb, AnyCollisionType))
+	}
+	keys = append(keys, collisionKey(AnyCollisionType, AnyCollisionType))
+
+	d := s.data()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var filters []collisionFilterEntry
+	for _, key := range keys {
+		filters = append(filters, d.collisionFilters[key]...)
+	}
+	return filters
+}
+
+//export postSolve
+func postSolve(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) {
+	space, arb := cpSpace(s), cpArbiter(a)
+	space.recordCollisionEvent(CollisionPostSolve, arb)
+
+	h, swap := space.findCollisionHandler(arb)
+	if nil == h {
+		return
+	}
+	if swap {
+		arb.swap()
+		defer arb.swap()
+	}
+
+	h.PostSolve(space, arb)
+}
+
+//export preSolve
+func preSolve(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) C.cpBool {
+	space, arb := cpSpace(s), cpArbiter(a)
+
+	for _, e := range space.findCollisionFilters(arb) {
+		if !inFilterOrder(arb, e, func() bool { return e.filter.PreSolve(space, arb) }) {
+			return boolToC(false)
+		}
+	}
+
+	h, swap := space.findCollisionHandler(arb)
+	if nil == h {
+		return boolToC(true)
+	}
+	if swap {
+		arb.swap()
+		defer arb.swap()
+	}
+
+	return boolToC(h.PreSolve(space, arb))
+}
+
+// inFilterOrder calls a function with the arbiter reporting shapes in the order
+// of a collision filter.
+func inFilterOrder(arb Arbiter, e collisionFilterEntry, f func() bool) bool {
+	sa, sb := arb.Shapes()
+	if collisionSwapped(e.a, e.b, sa.CollisionType(), sb.CollisionType()) {
+		arb.swap()
+		defer arb.swap()
+	}
+	return f()
+}
+
+//export separate
+func separate(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) {
+	space, arb := cpSpace(s), cpArbiter(a)
+	space.recordCollisionEvent(CollisionSeparate, arb)
+
+	for _, e := range space.findCollisionFilters(arb) {
+		if f, ok := e.filter.(collisionSeparateFilter); ok {
+			inFilterOrder(arb, e, func() bool {
+				f.Separate(space, arb)
+				return true
+			})
+		}
+	}
+
+	h, swap := space.findCollisionHandler(arb)
+	if nil == h {
+		return
+	}
+	if swap {
+		arb.swap()
+		defer arb.swap()
+	}
+
+	h.Separate(space, arb)
+}
+
+// syncCollisionHandler makes Chipmunk call the handler trampolines for a pair of
+// collision types if and only if a handler or a filter is set for them. Wildcard and
+// default handlers and filters and collision event streams are dispatched from Chipmunk's default handler.
+// Chipmunk's handlers can't be changed while the space is locked, so then it's
+// done in a post-step callback.
+func (s Space) syncCollisionHandler(key collisionTypePair) {
+	if s.IsLocked() {
+		s.AddPostStepCallback(func(s Space, _ interface{}) {
+			s.syncCollisionHandler(key)
+		}, collisionHandlerSync{key})
+		return
+	}
+
+	wildcard := AnyCollisionType == key.b
+	set := false
+
+	d := s.data()
+	d.mu.Lock()
+	if wildcard {
+		set = len(d.collisionEventStreams) > 0
+		for k := range d.collisionHandlers {
+			if AnyCollisionType == k.b {
+				set = true
+				break
+			}
+		}
+		for k := range d.collisionFilters {
+			if AnyCollisionType == k.b {
+				set = true
+				break
+			}
+		}
+	} else {
+		_, set = d.collisionHandlers[key]
+		set = set || len(d.collisionFilters[key]) > 0
+	}
+	d.mu.Unlock()
+
+	switch {
+	case wildcard && set:
+		C.space_set_default_collision_handler(s.c())
+	case wildcard:
+		C.cpSpaceSetDefaultCollisionHandler(s.c(), nil, nil, nil, nil, nil)
+	case set:
+		C.space_add_collision_handler(s.c(), key.a.c(), key.b.c())
+	default:
+		C.cpSpaceRemoveCollisionHandler(s.c(), key.a.c(), key.b.c())
+	}
+}
@@ -0,0 +1,196 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+// shapeTypes returns the collision types of the shapes of an arbiter.
+func shapeTypes(arb Arbiter) [2]CollisionType {
+	a, b := arb.Shapes()
+	return [2]CollisionType{a.CollisionType(), b.CollisionType()}
+}
+
+func Test_CollisionHandlerOrder(t *testing.T) {
+	for _, types := range [][2]CollisionType{{1, 2}, {2, 1}} {
+		s := SpaceNew()
+		s.SetGravity(VectNew(0.0, -100.0))
+
+		s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)).SetCollisionType(1)
+
+		b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+		b.SetPosition(VectNew(0.0, 0.9))
+		s.AddShape(CircleShapeNew(b, 1.0, Origin())).SetCollisionType(2)
+
+		var got [][2]CollisionType
+		s.SetCollisionHandler(types[0], types[1], CollisionHandlerFuncs{
+			BeginFunc: func(s Space, arb Arbiter) bool {
+				got = append(got, shapeTypes(arb))
+				return true
+			},
+		})
+		s.Step(1.0 / 60.0)
+
+		assert.Equal(t, [][2]CollisionType{types}, got)
+		s.Free()
+	}
+}
+
+func Test_CollisionHandlerWildcard(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)).SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	s.AddShape(CircleShapeNew(b, 1.0, Origin())).SetCollisionType(2)
+
+	var wildcard, exact, def [][2]CollisionType
+	record := func(calls *[][2]CollisionType) CollisionHandler {
+		return CollisionHandlerFuncs{
+			PreSolveFunc: func(s Space, arb Arbiter) bool {
+				*calls = append(*calls, shapeTypes(arb))
+				return true
+			},
+		}
+	}
+
+	s.SetCollisionHandler(AnyCollisionType, AnyCollisionType, record(&def))
+	s.SetCollisionHandler(AnyCollisionType, 2, record(&wildcard))
+	s.Step(1.0 / 60.0)
+
+	assert.Equal(t, [][2]CollisionType{{1, 2}}, wildcard)
+	assert.Equal(t, 0, len(def))
+
+	s.SetCollisionHandler(2, 1, record(&exact))
+	s.Step(1.0 / 60.0)
+
+	assert.Equal(t, 1, len(wildcard))
+	assert.Equal(t, [][2]CollisionType{{2, 1}}, exact)
+
+	s.RemoveCollisionHandler(1, 2)
+	s.RemoveCollisionHandler(2, AnyCollisionType)
+	s.Step(1.0 / 60.0)
+
+	assert.Equal(t, 1, len(wildcard))
+	assert.Equal(t, 1, len(exact))
+	assert.Equal(t, 1, len(def))
+
+	s.Free()
+}
+
+func Test_CollisionHandlerChangeWhileLocked(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)).SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	s.AddShape(CircleShapeNew(b, 1.0, Origin())).SetCollisionType(2)
+
+	first, second := 0, 0
+	s.SetCollisionHandler(1, 2, CollisionHandlerFuncs{
+		PreSolveFunc: func(s Space, arb Arbiter) bool {
+			first++
+			s.SetCollisionHandler(1, 2, CollisionHandlerFuncs{
+				PreSolveFunc: func(s Space, arb Arbiter) bool {
+					second++
+					s.RemoveCollisionHandler(2, 1)
+					return true
+				},
+			})
+			return true
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		s.Step(1.0 / 60.0)
+	}
+
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 1, second)
+
+	s.Free()
+}
+
+// recordFilter is a collision filter recording the collision types it's called for.
+type recordFilter struct {
+	calls  [][2]CollisionType
+	result bool
+}
+
+// PreSolve implements CollisionFilter.
+func (f *recordFilter) PreSolve(s Space, arb Arbiter) bool {
+	f.calls = append(f.calls, shapeTypes(arb))
+	return f.result
+}
+
+func Test_CollisionFilter(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)).SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	handler := &recordFilter{result: true}
+	s.SetCollisionHandler(1, 2, CollisionHandlerFuncs{PreSolveFunc: handler.PreSolve})
+
+	exact, wildcard := &recordFilter{result: true}, &recordFilter{result: true}
+	s.AddCollisionFilter(2, 1, exact)
+	s.AddCollisionFilter(1, AnyCollisionType, wildcard)
+	s.Step(1.0 / 60.0)
+
+	assert.Equal(t, [][2]CollisionType{{2, 1}}, exact.calls)
+	assert.Equal(t, [][2]CollisionType{{1, 2}}, wildcard.calls)
+	assert.Equal(t, [][2]CollisionType{{1, 2}}, handler.calls)
+
+	// a rejecting filter skips the handler and the collision
+	wildcard.result = false
+	for i := 0; i < 30; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Equal(t, 1, len(handler.calls))
+	assert.T(t, ball.Body().Position().Y < 0.0)
+
+	n, m := len(exact.calls), len(wildcard.calls)
+	s.RemoveCollisionFilter(1, AnyCollisionType, wildcard)
+	s.RemoveCollisionFilter(2, 1, exact)
+	ball.Body().SetPosition(VectNew(0.0, 0.9))
+	ball.Body().SetVelocity(Origin())
+	s.Step(1.0 / 60.0)
+
+	assert.Equal(t, n, len(exact.calls))
+	assert.Equal(t, m, len(wildcard.calls))
+	assert.Equal(t, 2, len(handler.calls))
+
+	s.Free()
+}
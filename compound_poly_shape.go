@@ -0,0 +1,122 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CompoundPolyShape is a concave polygon made of convex polygon shapes attached
+// to the same body. It's added to and removed from a space as a whole.
+type CompoundPolyShape struct {
+	Shapes []PolyShape
+	// Centroid is the center of gravity of the polygon in the coordinates of its
+	// vertices, which is at the body origin if the shape was created with a density.
+	Centroid Vect
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CompoundPolyShapeNew splits a simple polygon, optionally with holes, into
+// convex polygon shapes attached to a body (see ConvexDecomposition).
+// If density is positive, the mass and the moment of inertia of the body are set
+// to the ones of the compound shape and the shapes are moved so that their center
+// of gravity is at the body origin, like Body.RecomputeMassFromShapes does.
+// The body is moved by the centroid then, so the polygon keeps its place in the world.
+func CompoundPolyShapeNew(b Body, verts []Vect, holes [][]Vect, density float64) (CompoundPolyShape, error) {
+	pieces, err := ConvexDecomposition(verts, holes)
+	if err != nil {
+		return CompoundPolyShape{}, err
+	}
+
+	c := CompoundPolyShape{Shapes: make([]PolyShape, len(pieces))}
+	for i, p := range pieces {
+		c.Shapes[i] = PolyShapeNew(b, p, Origin())
+	}
+
+	if density > 0.0 {
+		props := c.MassProperties(density)
+		if props.Mass <= 0.0 {
+			return c, nil
+		}
+
+		for _, sh := range c.Shapes {
+			moveShape(sh, props.Centroid.Neg())
+		}
+
+		b.SetPosition(b.LocalToWorld(props.Centroid))
+		b.SetMass(props.Mass)
+		b.SetMoment(props.Moment)
+		c.Centroid = props.Centroid
+	}
+
+	return c, nil
+}
+
+// Free frees all the shapes.
+func (c CompoundPolyShape) Free() {
+	for _, sh := range c.Shapes {
+		sh.Free()
+	}
+}
+
+// MassProperties returns the combined mass properties of the shapes for a density.
+func (c CompoundPolyShape) MassProperties(density float64) MassProperties {
+	var props MassProperties
+	for _, sh := range c.Shapes {
+		props = props.Add(sh.MassProperties(density))
+	}
+	return props
+}
+
+// addToSpace adds all the shapes to space.
+func (c CompoundPolyShape) addToSpace(s Space) {
+	for _, sh := range c.Shapes {
+		s.AddShape(sh)
+	}
+}
+
+// containedInSpace returns true if the space contains all the shapes.
+func (c CompoundPolyShape) containedInSpace(s Space) bool {
+	for _, sh := range c.Shapes {
+		if !s.Contains(sh) {
+			return false
+		}
+	}
+	return len(c.Shapes) > 0
+}
+
+// removeFromSpace removes all the shapes from space.
+func (c CompoundPolyShape) removeFromSpace(s Space) {
+	for _, sh := range c.Shapes {
+		s.RemoveShape(sh)
+	}
+}
+
+// shapes returns the shapes.
+func (c CompoundPolyShape) shapes() []Shape {
+	shapes := make([]Shape, len(c.Shapes))
+	for i, sh := range c.Shapes {
+		shapes[i] = sh
+	}
+	return shapes
+}
@@ -0,0 +1,100 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"math"
+	"testing"
+)
+
+func Test_CompoundPolyShape(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	// an L shape made of two unit squares side by side and one on top
+	l := []Vect{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}
+	b := s.AddBody(BodyNew(1.0, 1.0))
+
+	c, err := CompoundPolyShapeNew(b, l, nil, 2.0)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(c.Shapes))
+	defer c.Free()
+
+	assert.T(t, nearlyEqual(6.0, b.Mass()))
+
+	// the center of gravity is at the body origin
+	centroid := VectNew(5.0/6.0, 5.0/6.0)
+	assert.T(t, c.Centroid.Sub(centroid).Length() < 1e-9)
+	assert.T(t, b.Position().Sub(centroid).Length() < 1e-9)
+
+	// each square has a moment of 2/6 about its own center
+	centers := []Vect{{0.5, 0.5}, {1.5, 0.5}, {0.5, 1.5}}
+	moment := 0.0
+	for _, p := range centers {
+		d := p.Sub(centroid)
+		moment += 2.0/6.0 + 2.0*d.Dot(d)
+	}
+	assert.T(t, nearlyEqual(moment, b.Moment()))
+
+	assert.T(t, !s.Contains(c))
+	s.Add(c)
+	assert.T(t, s.Contains(c))
+
+	p := b.RecomputeMassFromShapes(2.0, false)
+	assert.T(t, p.Centroid.Length() < 1e-9)
+	assert.T(t, nearlyEqual(moment, p.MomentAbout(Origin())))
+
+	s.Remove(c)
+	assert.T(t, !s.Contains(c))
+}
+
+func Test_CompoundPolyShapeBalance(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10.0, 0.0), VectNew(10.0, 0.0), 0.0))
+	ground.SetFriction(1.0)
+
+	// an L shape standing on the ground far from the body origin,
+	// it would tip over if it balanced on the origin
+	l := []Vect{{3, 0}, {5, 0}, {5, 1}, {4, 1}, {4, 2}, {3, 2}}
+	b := s.AddBody(BodyNew(1.0, 1.0))
+
+	c, err := CompoundPolyShapeNew(b, l, nil, 1.0)
+	assert.Equal(t, nil, err)
+	for _, sh := range c.Shapes {
+		sh.SetFriction(1.0)
+	}
+	s.Add(c)
+
+	for i := 0; i < 120; i++ {
+		s.Step(1.0 / 60.0)
+		assert.Tf(t, math.Abs(b.AngularVelocity()) < 1e-2, "body rotates: %v", b.AngularVelocity())
+	}
+	assert.Tf(t, math.Abs(b.Angle()) < 1e-2, "body tips over: %v", b.Angle())
+
+	c.Free()
+	s.Free()
+}
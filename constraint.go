@@ -28,6 +28,7 @@ WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 import "C"
 
 import (
+	"sync"
 	"unsafe"
 )
 
@@ -63,7 +64,8 @@ type constraintData struct {
 ////////////////////////////////////////////////////////////////////////////////
 
 var (
-	constraintDataMap = make(map[constraintBase]*constraintData)
+	constraintDataMap   = make(map[constraintBase]*constraintData)
+	constraintDataMutex sync.RWMutex
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -105,9 +107,15 @@ func (c constraintBase) ErrorBias() float64 {
 }
 
 // Free frees the constraint.
+// A constraint removed from a locked space is freed once the removal is applied.
 func (c constraintBase) Free() {
-	constraintDataMap[c] = nil
-	C.cpConstraintFree(c.c())
+	freeAfterPendingOps(unsafe.Pointer(c.c()), func() {
+		constraintDataMutex.Lock()
+		delete(constraintDataMap, c)
+		constraintDataMutex.Unlock()
+
+		C.cpConstraintFree(c.c())
+	})
 }
 
 // Impulse returns the last impulse applied by this constraint.
@@ -147,14 +155,14 @@ func (c constraintBase) SetMaxForce(f float64) {
 // SetPostSolveFunc sets a callback function type that gets called after solving a joint.
 // Use the applied impulse to perform effects like breakable joints.
 func (c constraintBase) SetPostSolveFunc(f func(c Constraint, s Space)) {
-	constraintDataMap[c].postSolveFunc = f
+	c.data().postSolveFunc = f
 	C.constraint_set_postsolve_func(c.c(), boolToC(f != nil))
 }
 
 // SetPreSolveFunc sets a callback function type that gets called before solving a joint.
 // Animate your joint anchors, update your motor torque, etc.
 func (c constraintBase) SetPreSolveFunc(f func(c Constraint, s Space)) {
-	constraintDataMap[c].preSolveFunc = f
+	c.data().preSolveFunc = f
 	C.constraint_set_presolve_func(c.c(), boolToC(f != nil))
 }
 
@@ -162,7 +170,7 @@ func (c constraintBase) SetPreSolveFunc(f func(c Constraint, s Space)) {
 // Generally this points to your the game object so you can access it
 // when given a Constraint reference in a callback.
 func (c constraintBase) SetUserData(data interface{}) {
-	constraintDataMap[c].userData = data
+	c.data().userData = data
 }
 
 // Space returns space the constraint was added to or 0 if the constraint
@@ -173,7 +181,7 @@ func (c constraintBase) Space() Space {
 
 // UserData returns user defined data.
 func (c constraintBase) UserData() interface{} {
-	return constraintDataMap[c].userData
+	return c.data().userData
 }
 
 // addToSpace adds a constraint to space.
@@ -189,14 +197,14 @@ func (c constraintBase) c() *C.cpConstraint {
 //export constraintPostsolve
 func constraintPostsolve(c *C.cpConstraint, s *C.cpSpace) {
 	constraint := cpConstraint(c)
-	data := constraintDataMap[cpConstraintBase(c)]
+	data := cpConstraintBase(c).data()
 	data.postSolveFunc(constraint, cpSpace(s))
 }
 
 //export constraintPresolve
 func constraintPresolve(c *C.cpConstraint, s *C.cpSpace) {
 	constraint := cpConstraint(c)
-	data := constraintDataMap[cpConstraintBase(c)]
+	data := cpConstraintBase(c).data()
 	data.preSolveFunc(constraint, cpSpace(s))
 }
 
@@ -247,10 +255,21 @@ func cpConstraintBase(ct *C.cpConstraint) constraintBase {
 // cpConstraintBaseNew creates a new constraintBase out of C.cpConstraint pointer.
 func cpConstraintBaseNew(ct *C.cpConstraint) constraintBase {
 	c := cpConstraintBase(ct)
+
+	constraintDataMutex.Lock()
 	constraintDataMap[c] = &constraintData{}
+	constraintDataMutex.Unlock()
+
 	return c
 }
 
+// data returns the Go side state of the constraint.
+func (c constraintBase) data() *constraintData {
+	constraintDataMutex.RLock()
+	defer constraintDataMutex.RUnlock()
+	return constraintDataMap[c]
+}
+
 // removeFromSpace removes a constraint from space.
 func (c constraintBase) removeFromSpace(s Space) {
 	s.RemoveConstraint(c)
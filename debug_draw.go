@@ -0,0 +1,195 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"image/color"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Drawer is a renderer used by DebugDraw. All coordinates passed to a drawer
+// are in world space.
+type Drawer interface {
+	// Flags returns which parts of a space should be drawn.
+	Flags() DebugDrawFlags
+	// DrawCircle draws a circle. The angle is the rotation of its body, drawn
+	// as a line from the center to the circle outline.
+	DrawCircle(center Vect, angle, radius float64, outline, fill color.Color)
+	// DrawSegment draws a thin line.
+	DrawSegment(a, b Vect, c color.Color)
+	// DrawFatSegment draws a segment with rounded caps.
+	DrawFatSegment(a, b Vect, radius float64, outline, fill color.Color)
+	// DrawPolygon draws a convex polygon with optionally rounded corners.
+	DrawPolygon(verts []Vect, radius float64, outline, fill color.Color)
+	// DrawDot draws a point of specific size (in pixels).
+	DrawDot(size float64, pos Vect, c color.Color)
+}
+
+// DebugDrawFlags selects which parts of a space DebugDraw emits.
+type DebugDrawFlags uint
+
+// DebugDraw flags.
+const (
+	DebugDrawShapes DebugDrawFlags = 1 << iota
+	DebugDrawConstraints
+	DebugDrawContacts
+	DebugDrawBBs
+
+	DebugDrawAll = DebugDrawShapes | DebugDrawConstraints | DebugDrawContacts | DebugDrawBBs
+)
+
+// Colors used by DebugDraw.
+var (
+	DebugColorOutline    = color.NRGBA{200, 210, 230, 255}
+	DebugColorDynamic    = color.NRGBA{82, 163, 217, 255}
+	DebugColorRogue      = color.NRGBA{217, 163, 82, 255}
+	DebugColorSleeping   = color.NRGBA{110, 110, 110, 255}
+	DebugColorStatic     = color.NRGBA{70, 80, 95, 255}
+	DebugColorSensor     = color.NRGBA{255, 255, 255, 40}
+	DebugColorConstraint = color.NRGBA{128, 217, 82, 255}
+	DebugColorContact    = color.NRGBA{230, 60, 50, 255}
+	DebugColorBB         = color.NRGBA{77, 128, 255, 255}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DebugDraw draws the shapes, constraints, contact points and bounding boxes
+// of a space with a drawer. Shapes are colored by the state of their body.
+// It must not be called while the space is locked.
+func DebugDraw(s Space, d Drawer) {
+	flags := d.Flags()
+
+	if 0 != flags&(DebugDrawShapes|DebugDrawBBs) {
+		s.EachShape(func(sh Shape) {
+			if 0 != flags&DebugDrawShapes {
+				debugDrawShape(sh, d)
+			}
+			if 0 != flags&DebugDrawBBs {
+				debugDrawBB(sh.BB(), d)
+			}
+		})
+	}
+
+	if 0 != flags&DebugDrawConstraints {
+		s.EachConstraint(func(c Constraint) {
+			debugDrawConstraint(c, d)
+		})
+	}
+
+	if 0 != flags&DebugDrawContacts {
+		seen := map[Arbiter]bool{}
+		s.EachBody(func(b Body) {
+			b.EachArbiter(func(b Body, arb Arbiter) {
+				if seen[arb] {
+					return
+				}
+				seen[arb] = true
+
+				for _, cp := range arb.ContactPoints() {
+					d.DrawDot(4.0, cp.Point, DebugColorContact)
+				}
+			})
+		})
+	}
+}
+
+// debugColor returns a fill color of a shape according to the state of its body.
+func debugColor(sh Shape) color.Color {
+	b := sh.Body()
+
+	switch {
+	case sh.Sensor():
+		return DebugColorSensor
+	case b.IsStatic():
+		return DebugColorStatic
+	case b.IsSleeping():
+		return DebugColorSleeping
+	case b.IsRogue():
+		return DebugColorRogue
+	}
+
+	return DebugColorDynamic
+}
+
+// debugDrawBB draws a bounding box as a series of segments.
+func debugDrawBB(bb BB, d Drawer) {
+	lb, rb := VectNew(bb.l, bb.b), VectNew(bb.r, bb.b)
+	rt, lt := VectNew(bb.r, bb.t), VectNew(bb.l, bb.t)
+
+	d.DrawSegment(lb, rb, DebugColorBB)
+	d.DrawSegment(rb, rt, DebugColorBB)
+	d.DrawSegment(rt, lt, DebugColorBB)
+	d.DrawSegment(lt, lb, DebugColorBB)
+}
+
+// debugDrawConstraint draws the anchors of a constraint and a line between them.
+// Constraints without anchors (rotary ones) are not drawn.
+func debugDrawConstraint(c Constraint, d Drawer) {
+	a, b := c.A(), c.B()
+
+	var p1, p2 Vect
+
+	switch ct := c.(type) {
+	case PinJoint:
+		p1, p2 = a.LocalToWorld(ct.Anchr1()), b.LocalToWorld(ct.Anchr2())
+	case SlideJoint:
+		p1, p2 = a.LocalToWorld(ct.Anchr1()), b.LocalToWorld(ct.Anchr2())
+	case PivotJoint:
+		p1, p2 = a.LocalToWorld(ct.Anchr1()), b.LocalToWorld(ct.Anchr2())
+	case DampedSpring:
+		p1, p2 = a.LocalToWorld(ct.Anchr1()), b.LocalToWorld(ct.Anchr2())
+	case GrooveJoint:
+		ga, gb := a.LocalToWorld(ct.GrooveA()), a.LocalToWorld(ct.GrooveB())
+		d.DrawSegment(ga, gb, DebugColorConstraint)
+		p1, p2 = ga.Add(gb).Mul(0.5), b.LocalToWorld(ct.Anchr2())
+	default:
+		return
+	}
+
+	d.DrawDot(5.0, p1, DebugColorConstraint)
+	d.DrawDot(5.0, p2, DebugColorConstraint)
+	d.DrawSegment(p1, p2, DebugColorConstraint)
+}
+
+// debugDrawShape draws a shape in world coordinates.
+func debugDrawShape(sh Shape, d Drawer) {
+	b := sh.Body()
+	fill := debugColor(sh)
+
+	switch s := sh.(type) {
+	case CircleShape:
+		d.DrawCircle(b.LocalToWorld(s.Offset()), b.Angle(), s.Radius(), DebugColorOutline, fill)
+
+	case SegmentShape:
+		d.DrawFatSegment(b.LocalToWorld(s.A()), b.LocalToWorld(s.B()), s.Radius(), DebugColorOutline, fill)
+
+	case PolyShape:
+		verts := make([]Vect, s.NumVerts())
+		for i := range verts {
+			verts[i] = b.LocalToWorld(s.VertLocal(i))
+		}
+		d.DrawPolygon(verts, s.Radius(), DebugColorOutline, fill)
+	}
+}
@@ -0,0 +1,99 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"image/color"
+	"testing"
+)
+
+type recordDrawer struct {
+	flags    DebugDrawFlags
+	circles  []color.Color
+	segments int
+	fat      int
+	polygons int
+	dots     []color.Color
+}
+
+func (d *recordDrawer) Flags() DebugDrawFlags { return d.flags }
+
+func (d *recordDrawer) DrawCircle(center Vect, angle, radius float64, outline, fill color.Color) {
+	d.circles = append(d.circles, fill)
+}
+
+func (d *recordDrawer) DrawSegment(a, b Vect, c color.Color) { d.segments++ }
+
+func (d *recordDrawer) DrawFatSegment(a, b Vect, radius float64, outline, fill color.Color) {
+	d.fat++
+}
+
+func (d *recordDrawer) DrawPolygon(verts []Vect, radius float64, outline, fill color.Color) {
+	d.polygons++
+}
+
+func (d *recordDrawer) DrawDot(size float64, pos Vect, c color.Color) {
+	d.dots = append(d.dots, c)
+}
+
+func Test_DebugDraw(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+
+	box := s.AddBody(BodyNew(1.0, MomentForBox(1.0, 1.0, 1.0)))
+	box.SetPosition(VectNew(5.0, 5.0))
+	s.AddShape(BoxShapeNew(box, 1.0, 1.0))
+
+	s.AddConstraint(PinJointNew(b, box, Origin(), Origin()))
+
+	s.Step(1.0 / 60.0)
+
+	d := &recordDrawer{flags: DebugDrawAll}
+	DebugDraw(s, d)
+
+	assert.Equal(t, []color.Color{DebugColorDynamic}, d.circles)
+	assert.Equal(t, 1, d.fat)
+	assert.Equal(t, 1, d.polygons)
+	// three bounding boxes and a pin joint
+	assert.Equal(t, 3*4+1, d.segments)
+	assert.T(t, len(d.dots) > 2)
+	assert.Equal(t, DebugColorConstraint, d.dots[0])
+	assert.Equal(t, DebugColorContact, d.dots[len(d.dots)-1])
+
+	b.Sleep()
+	d = &recordDrawer{flags: DebugDrawShapes}
+	DebugDraw(s, d)
+
+	assert.Equal(t, []color.Color{DebugColorSleeping}, d.circles)
+	assert.Equal(t, 0, d.segments)
+	assert.Equal(t, 0, len(d.dots))
+
+	s.Free()
+}
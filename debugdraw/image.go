@@ -0,0 +1,199 @@
+package debugdraw
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Image is a chipmunk.Drawer which rasterizes into an image.RGBA.
+// Drawing is aliased and blended over the existing contents of the image.
+type Image struct {
+	img   *image.RGBA
+	view  view
+	flags chipmunk.DebugDrawFlags
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewImage creates a drawer which shows a bounding box of the world in an image.
+func NewImage(img *image.RGBA, bb chipmunk.BB) *Image {
+	size := img.Bounds().Size()
+	return &Image{img: img, view: newView(bb, size.X, size.Y), flags: chipmunk.DebugDrawAll}
+}
+
+// DrawCircle implements chipmunk.Drawer.
+func (d *Image) DrawCircle(center chipmunk.Vect, angle, radius float64, outline, fill color.Color) {
+	cx, cy := d.view.point(center)
+	d.raster([]float64{cx, cy}, math.Max(d.view.length(radius), 0.5), func(x, y float64) float64 {
+		return math.Hypot(x-cx, y-cy)
+	}, outline, fill)
+	d.DrawSegment(center, center.Add(chipmunk.VectForAngle(angle).Mul(radius)), outline)
+}
+
+// DrawDot implements chipmunk.Drawer.
+func (d *Image) DrawDot(size float64, pos chipmunk.Vect, c color.Color) {
+	cx, cy := d.view.point(pos)
+	d.raster([]float64{cx, cy}, math.Max(size/2.0, 0.5), func(x, y float64) float64 {
+		return math.Hypot(x-cx, y-cy)
+	}, nil, c)
+}
+
+// DrawFatSegment implements chipmunk.Drawer.
+func (d *Image) DrawFatSegment(a, b chipmunk.Vect, radius float64, outline, fill color.Color) {
+	x1, y1 := d.view.point(a)
+	x2, y2 := d.view.point(b)
+	d.raster([]float64{x1, y1, x2, y2}, math.Max(d.view.length(radius), 0.5), func(x, y float64) float64 {
+		return segmentDist(x, y, x1, y1, x2, y2)
+	}, outline, fill)
+}
+
+// DrawPolygon implements chipmunk.Drawer.
+func (d *Image) DrawPolygon(verts []chipmunk.Vect, radius float64, outline, fill color.Color) {
+	if 0 == len(verts) {
+		return
+	}
+
+	points := make([]float64, 0, 2*len(verts))
+	for _, v := range verts {
+		x, y := d.view.point(v)
+		points = append(points, x, y)
+	}
+
+	d.raster(points, math.Max(d.view.length(radius), 0.5), func(x, y float64) float64 {
+		return polygonDist(x, y, points)
+	}, outline, fill)
+}
+
+// DrawSegment implements chipmunk.Drawer.
+func (d *Image) DrawSegment(a, b chipmunk.Vect, c color.Color) {
+	x1, y1 := d.view.point(a)
+	x2, y2 := d.view.point(b)
+	d.raster([]float64{x1, y1, x2, y2}, 0.5, func(x, y float64) float64 {
+		return segmentDist(x, y, x1, y1, x2, y2)
+	}, nil, c)
+}
+
+// Flags implements chipmunk.Drawer.
+func (d *Image) Flags() chipmunk.DebugDrawFlags {
+	return d.flags
+}
+
+// SetFlags selects which parts of a space are drawn (chipmunk.DebugDrawAll by default).
+func (d *Image) SetFlags(flags chipmunk.DebugDrawFlags) {
+	d.flags = flags
+}
+
+// blend draws a color over a pixel.
+func (d *Image) blend(x, y int, c color.Color) {
+	sr, sg, sb, sa := c.RGBA()
+	if 0 == sa {
+		return
+	}
+
+	i := d.img.PixOffset(x, y)
+	p := d.img.Pix[i : i+4 : i+4]
+	k := 0xffff - sa
+
+	p[0] = uint8((sr + uint32(p[0])*0x101*k/0xffff) >> 8)
+	p[1] = uint8((sg + uint32(p[1])*0x101*k/0xffff) >> 8)
+	p[2] = uint8((sb + uint32(p[2])*0x101*k/0xffff) >> 8)
+	p[3] = uint8((sa + uint32(p[3])*0x101*k/0xffff) >> 8)
+}
+
+// raster fills every pixel whose center lies within distance r of a shape
+// given by its distance function dist (negative inside the shape). Pixels within
+// a pixel of the boundary get the outline color, the rest get the fill color.
+// Either color may be nil. The points are only used to bound the search.
+func (d *Image) raster(points []float64, r float64, dist func(x, y float64) float64, outline, fill color.Color) {
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+
+	for i := 0; i < len(points); i += 2 {
+		x0, x1 = math.Min(x0, points[i]), math.Max(x1, points[i])
+		y0, y1 = math.Min(y0, points[i+1]), math.Max(y1, points[i+1])
+	}
+
+	bounds := d.img.Bounds()
+	rect := image.Rect(
+		bounds.Min.X+int(math.Floor(x0-r)), bounds.Min.Y+int(math.Floor(y0-r)),
+		bounds.Min.X+int(math.Ceil(x1+r))+1, bounds.Min.Y+int(math.Ceil(y1+r))+1,
+	).Intersect(bounds)
+
+	for py := rect.Min.Y; py < rect.Max.Y; py++ {
+		for px := rect.Min.X; px < rect.Max.X; px++ {
+			dd := dist(float64(px-bounds.Min.X)+0.5, float64(py-bounds.Min.Y)+0.5)
+
+			switch {
+			case dd > r:
+			case nil != outline && dd > r-1.0:
+				d.blend(px, py, outline)
+			case nil != fill:
+				d.blend(px, py, fill)
+			}
+		}
+	}
+}
+
+// polygonDist returns the signed distance from a point to a polygon
+// given as x, y pairs (negative inside).
+func polygonDist(x, y float64, points []float64) float64 {
+	n := len(points)
+	dist := math.Inf(1)
+	inside := false
+
+	for i, j := 0, n-2; i < n; j, i = i, i+2 {
+		xi, yi, xj, yj := points[i], points[i+1], points[j], points[j+1]
+
+		dist = math.Min(dist, segmentDist(x, y, xj, yj, xi, yi))
+
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	if inside {
+		return -dist
+	}
+
+	return dist
+}
+
+// segmentDist returns the distance from a point to a segment.
+func segmentDist(x, y, x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	t := 0.0
+
+	if l := dx*dx + dy*dy; l > 0.0 {
+		t = math.Max(0.0, math.Min(1.0, ((x-x1)*dx+(y-y1)*dy)/l))
+	}
+
+	return math.Hypot(x-(x1+t*dx), y-(y1+t*dy))
+}
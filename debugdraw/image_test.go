@@ -0,0 +1,77 @@
+package debugdraw
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/ianremmler/chipmunk"
+)
+
+func drawScene() chipmunk.Space {
+	s := chipmunk.SpaceNew()
+	s.AddShape(chipmunk.SegmentShapeNew(s.StaticBody(), chipmunk.VectNew(-2, -1.5), chipmunk.VectNew(2, -1.5), 0.25))
+
+	b := s.AddBody(chipmunk.BodyNew(1.0, chipmunk.MomentForCircle(1.0, 0.0, 1.0, chipmunk.Origin())))
+	s.AddShape(chipmunk.CircleShapeNew(b, 1.0, chipmunk.Origin()))
+
+	return s
+}
+
+func Test_ImageDraw(t *testing.T) {
+	s := drawScene()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+
+	d := NewImage(img, chipmunk.BBNew(-2, -2, 2, 2))
+	d.SetFlags(chipmunk.DebugDrawShapes)
+	chipmunk.DebugDraw(s, d)
+
+	at := func(x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	}
+
+	assert.Equal(t, chipmunk.DebugColorDynamic, at(15, 25))
+	assert.Equal(t, chipmunk.DebugColorStatic, at(20, 35))
+	assert.Equal(t, chipmunk.DebugColorOutline, at(20, 10))
+	assert.Equal(t, color.NRGBA{}, at(1, 1))
+
+	s.Free()
+}
+
+func Test_ImageDrawOffsetBounds(t *testing.T) {
+	s := drawScene()
+	img := image.NewRGBA(image.Rect(100, 100, 140, 140))
+
+	chipmunk.DebugDraw(s, NewImage(img, chipmunk.BBNew(-2, -2, 2, 2)))
+
+	_, _, _, a := img.At(115, 125).RGBA()
+	assert.NotEqual(t, uint32(0), a)
+	_, _, _, a = img.At(101, 101).RGBA()
+	assert.Equal(t, uint32(0), a)
+
+	s.Free()
+}
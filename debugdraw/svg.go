@@ -0,0 +1,140 @@
+package debugdraw
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SVG is a chipmunk.Drawer which writes an SVG document.
+type SVG struct {
+	w     io.Writer
+	view  view
+	flags chipmunk.DebugDrawFlags
+	err   error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewSVG creates an SVG drawer of specific size (in pixels) which shows a bounding
+// box of the world and writes the document header to w.
+// Close must be called to finish the document.
+func NewSVG(w io.Writer, bb chipmunk.BB, width, height int) *SVG {
+	d := &SVG{w: w, view: newView(bb, width, height), flags: chipmunk.DebugDrawAll}
+	d.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+	return d
+}
+
+// Close finishes the document and returns the first write error, if any.
+func (d *SVG) Close() error {
+	d.printf("</svg>\n")
+	return d.err
+}
+
+// DrawCircle implements chipmunk.Drawer.
+func (d *SVG) DrawCircle(center chipmunk.Vect, angle, radius float64, outline, fill color.Color) {
+	x, y := d.view.point(center)
+	d.printf(`<circle cx="%g" cy="%g" r="%g" %s %s/>`+"\n",
+		x, y, d.view.length(radius), paint("fill", fill), paint("stroke", outline))
+	d.DrawSegment(center, center.Add(chipmunk.VectForAngle(angle).Mul(radius)), outline)
+}
+
+// DrawDot implements chipmunk.Drawer.
+func (d *SVG) DrawDot(size float64, pos chipmunk.Vect, c color.Color) {
+	x, y := d.view.point(pos)
+	d.printf(`<circle cx="%g" cy="%g" r="%g" %s/>`+"\n", x, y, size/2.0, paint("fill", c))
+}
+
+// DrawFatSegment implements chipmunk.Drawer.
+func (d *SVG) DrawFatSegment(a, b chipmunk.Vect, radius float64, outline, fill color.Color) {
+	if r := d.view.length(radius); r > 0.5 {
+		x1, y1 := d.view.point(a)
+		x2, y2 := d.view.point(b)
+		d.printf(`<line x1="%g" y1="%g" x2="%g" y2="%g" %s stroke-width="%g" stroke-linecap="round"/>`+"\n",
+			x1, y1, x2, y2, paint("stroke", fill), 2.0*r)
+	}
+	d.DrawSegment(a, b, outline)
+}
+
+// DrawPolygon implements chipmunk.Drawer.
+func (d *SVG) DrawPolygon(verts []chipmunk.Vect, radius float64, outline, fill color.Color) {
+	points := ""
+	for i, v := range verts {
+		x, y := d.view.point(v)
+		if i > 0 {
+			points += " "
+		}
+		points += fmt.Sprintf("%g,%g", x, y)
+	}
+
+	if r := d.view.length(radius); r > 0.5 {
+		d.printf(`<polygon points="%s" %s stroke-width="%g" stroke-linejoin="round"/>`+"\n",
+			points, paint("stroke", fill), 2.0*r)
+	}
+	d.printf(`<polygon points="%s" %s %s/>`+"\n", points, paint("fill", fill), paint("stroke", outline))
+}
+
+// DrawSegment implements chipmunk.Drawer.
+func (d *SVG) DrawSegment(a, b chipmunk.Vect, c color.Color) {
+	x1, y1 := d.view.point(a)
+	x2, y2 := d.view.point(b)
+	d.printf(`<line x1="%g" y1="%g" x2="%g" y2="%g" %s/>`+"\n", x1, y1, x2, y2, paint("stroke", c))
+}
+
+// Flags implements chipmunk.Drawer.
+func (d *SVG) Flags() chipmunk.DebugDrawFlags {
+	return d.flags
+}
+
+// SetFlags selects which parts of a space are drawn (chipmunk.DebugDrawAll by default).
+func (d *SVG) SetFlags(flags chipmunk.DebugDrawFlags) {
+	d.flags = flags
+}
+
+// paint converts a color to SVG paint attributes of specific kind (fill or stroke).
+func paint(attr string, c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	s := fmt.Sprintf(`%s="rgb(%d,%d,%d)"`, attr, n.R, n.G, n.B)
+
+	if n.A < math.MaxUint8 {
+		s += fmt.Sprintf(` %s-opacity="%.3g"`, attr, float64(n.A)/math.MaxUint8)
+	}
+
+	return s
+}
+
+// printf writes to the underlying writer unless a previous write has failed.
+func (d *SVG) printf(format string, args ...interface{}) {
+	if nil == d.err {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
@@ -0,0 +1,67 @@
+package debugdraw
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/ianremmler/chipmunk"
+)
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func Test_SVGDraw(t *testing.T) {
+	s := drawScene()
+	var buf bytes.Buffer
+
+	d := NewSVG(&buf, chipmunk.BBNew(-2, -2, 2, 2), 40, 40)
+	chipmunk.DebugDraw(s, d)
+	assert.Equal(t, nil, d.Close())
+
+	out := buf.String()
+	assert.T(t, strings.HasPrefix(out, `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="40"`))
+	assert.T(t, strings.HasSuffix(out, "</svg>\n"))
+	assert.T(t, strings.Contains(out, `<circle cx="20" cy="20" r="10" fill="rgb(82,163,217)"`))
+	assert.T(t, strings.Contains(out, `stroke-width="5" stroke-linecap="round"`))
+
+	s.Free()
+}
+
+func Test_SVGWriteError(t *testing.T) {
+	s := drawScene()
+
+	d := NewSVG(failWriter{}, chipmunk.BBNew(-2, -2, 2, 2), 40, 40)
+	chipmunk.DebugDraw(s, d)
+	assert.NotEqual(t, nil, d.Close())
+
+	s.Free()
+}
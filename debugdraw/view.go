@@ -0,0 +1,73 @@
+/*
+Package debugdraw provides chipmunk.Drawer backends which need no GPU:
+an SVG writer and an image.RGBA rasterizer.
+
+	img := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	chipmunk.DebugDraw(space, debugdraw.NewImage(img, chipmunk.BBNew(-320, -240, 320, 240)))
+*/
+package debugdraw
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"math"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// view maps world coordinates to pixel coordinates.
+// The world y axis points up, the pixel one points down.
+type view struct {
+	bb     chipmunk.BB
+	scale  float64
+	dx, dy float64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// newView creates a view which fits a bounding box of the world into
+// width x height pixels preserving the aspect ratio.
+func newView(bb chipmunk.BB, width, height int) view {
+	w, h := bb.Right()-bb.Left(), bb.Top()-bb.Bottom()
+	scale := math.Min(float64(width)/w, float64(height)/h)
+
+	return view{
+		bb:    bb,
+		scale: scale,
+		dx:    (float64(width) - w*scale) / 2.0,
+		dy:    (float64(height) - h*scale) / 2.0,
+	}
+}
+
+// length converts a length in world units to pixels.
+func (v view) length(l float64) float64 {
+	return l * v.scale
+}
+
+// point converts a point in world coordinates to pixel coordinates.
+func (v view) point(p chipmunk.Vect) (float64, float64) {
+	return v.dx + (p.X-v.bb.Left())*v.scale, v.dy + (v.bb.Top()-p.Y)*v.scale
+}
@@ -0,0 +1,434 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// decomposeEpsilon is the tolerance of the decomposition geometric predicates,
+// relative to the squared extent of the polygon.
+const decomposeEpsilon = 1e-12
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ConvexDecomposition splits a simple polygon, optionally with holes, into convex
+// polygons suitable for PolyShapeNew (wound clockwise, see PolyValidate).
+// The outline and the holes may have any winding, must not intersect themselves
+// or each other and the holes must lie inside the outline.
+// The polygon is triangulated by ear clipping, then the triangles are merged into
+// convex pieces (Hertel-Mehlhorn), which are at most four times as many as the optimum.
+func ConvexDecomposition(verts []Vect, holes [][]Vect) ([][]Vect, error) {
+	const op = "ConvexDecomposition"
+
+	eps := decomposeTolerance(verts)
+	outline := cleanPolygon(verts, eps)
+	if len(outline) < 3 {
+		return nil, checkError(op, fmt.Errorf("%w: polygon needs at least 3 distinct vertices",
+			ErrInvalidArgument))
+	}
+	if signedArea(outline) < 0.0 {
+		reverseVerts(outline)
+	}
+
+	var inner [][]Vect
+	for i, h := range holes {
+		h = cleanPolygon(h, eps)
+		if len(h) < 3 {
+			return nil, checkError(op, fmt.Errorf("%w: hole %d needs at least 3 distinct vertices",
+				ErrInvalidArgument, i))
+		}
+		if signedArea(h) > 0.0 {
+			reverseVerts(h)
+		}
+		inner = append(inner, h)
+	}
+
+	poly, err := bridgeHoles(outline, inner)
+	if err != nil {
+		return nil, checkError(op, err)
+	}
+
+	triangles, err := earClip(poly, eps)
+	if err != nil {
+		return nil, checkError(op, err)
+	}
+
+	pieces := mergeConvex(triangles, eps)
+	for _, p := range pieces {
+		// Chipmunk expects a clockwise winding
+		reverseVerts(p)
+	}
+
+	return pieces, nil
+}
+
+// bridgeHoles connects the holes (wound clockwise) to the outline (wound
+// counter-clockwise) with zero width bridges, making a single weakly simple polygon.
+func bridgeHoles(poly []Vect, holes [][]Vect) ([]Vect, error) {
+	// rightmost holes first, so the bridges don't cross the holes left to connect
+	sort.SliceStable(holes, func(i, j int) bool {
+		return holes[i][rightmostVert(holes[i])].X > holes[j][rightmostVert(holes[j])].X
+	})
+
+	for n, hole := range holes {
+		m := rightmostVert(hole)
+		p := bridgeVert(poly, hole[m], holes[n:])
+		if p < 0 {
+			return nil, fmt.Errorf("%w: hole %v is not inside the polygon", ErrInvalidArgument, hole[m])
+		}
+
+		merged := make([]Vect, 0, len(poly)+len(hole)+2)
+		merged = append(merged, poly[:p+1]...)
+		for i := 0; i <= len(hole); i++ {
+			merged = append(merged, hole[(m+i)%len(hole)])
+		}
+		merged = append(merged, poly[p:]...)
+		poly = merged
+	}
+
+	return poly, nil
+}
+
+// bridgeVert returns the index of the closest vertex of a polygon visible from
+// a point inside it, or -1 if there is no such vertex.
+func bridgeVert(poly []Vect, m Vect, holes [][]Vect) int {
+	idx := make([]int, len(poly))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return poly[idx[i]].Sub(m).Dot(poly[idx[i]].Sub(m)) < poly[idx[j]].Sub(m).Dot(poly[idx[j]].Sub(m))
+	})
+
+	for _, i := range idx {
+		n := len(poly)
+		if !inCone(poly[(i+n-1)%n], poly[i], poly[(i+1)%n], m) {
+			continue
+		}
+		if crossesPolygon(m, poly[i], poly) {
+			continue
+		}
+
+		visible := true
+		for _, h := range holes {
+			if crossesPolygon(m, poly[i], h) {
+				visible = false
+				break
+			}
+		}
+		if visible {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// cleanPolygon returns a copy of a polygon without repeated and collinear vertices.
+func cleanPolygon(verts []Vect, eps float64) []Vect {
+	poly := make([]Vect, 0, len(verts))
+	for _, v := range verts {
+		if 0 == len(poly) || poly[len(poly)-1] != v {
+			poly = append(poly, v)
+		}
+	}
+	for len(poly) > 1 && poly[0] == poly[len(poly)-1] {
+		poly = poly[:len(poly)-1]
+	}
+
+	for changed := true; changed && len(poly) >= 3; {
+		changed = false
+		for i := 0; i < len(poly) && len(poly) >= 3; i++ {
+			n := len(poly)
+			if math.Abs(turn(poly[(i+n-1)%n], poly[i], poly[(i+1)%n])) <= eps {
+				poly = append(poly[:i], poly[i+1:]...)
+				changed = true
+				i--
+			}
+		}
+	}
+
+	return poly
+}
+
+// decomposeTolerance returns the tolerance of the geometric predicates, which
+// compare cross products, for a polygon.
+func decomposeTolerance(verts []Vect) float64 {
+	lo, hi := VectNew(math.Inf(1), math.Inf(1)), VectNew(math.Inf(-1), math.Inf(-1))
+	for _, v := range verts {
+		lo = VectNew(math.Min(lo.X, v.X), math.Min(lo.Y, v.Y))
+		hi = VectNew(math.Max(hi.X, v.X), math.Max(hi.Y, v.Y))
+	}
+
+	extent := math.Max(hi.X-lo.X, hi.Y-lo.Y)
+	if !(extent > 0.0) || math.IsInf(extent, 0) {
+		return decomposeEpsilon
+	}
+	return decomposeEpsilon * extent * extent
+}
+
+// crossesPolygon returns true if the segment a-b properly crosses an edge of a polygon.
+// Touching an edge at a or b doesn't count.
+func crossesPolygon(a, b Vect, poly []Vect) bool {
+	for i := range poly {
+		c, d := poly[i], poly[(i+1)%len(poly)]
+		if c == a || c == b || d == a || d == b {
+			continue
+		}
+		if segmentsIntersect(a, b, c, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// earClip triangulates a polygon wound counter-clockwise.
+func earClip(poly []Vect, eps float64) ([][]Vect, error) {
+	verts := append([]Vect(nil), poly...)
+	triangles := make([][]Vect, 0, len(verts)-2)
+
+	for len(verts) > 3 {
+		n := len(verts)
+		ear := -1
+
+		// vertices touching an ear are only allowed if there is no other ear,
+		// which may happen with collinear vertices
+		for _, strict := range []bool{false, true} {
+			for i := 0; i < n && ear < 0; i++ {
+				a, b, c := verts[(i+n-1)%n], verts[i], verts[(i+1)%n]
+				t := turn(a, b, c)
+
+				if math.Abs(t) <= eps {
+					// a degenerate vertex, e.g. at the end of a bridge, is dropped
+					ear = i
+				} else if t > 0.0 && isEar(verts, a, b, c, strict) {
+					ear = i
+					triangles = append(triangles, []Vect{a, b, c})
+				}
+			}
+			if ear >= 0 {
+				break
+			}
+		}
+
+		if ear < 0 {
+			return nil, fmt.Errorf("%w: polygon is not simple", ErrInvalidArgument)
+		}
+		verts = append(verts[:ear], verts[ear+1:]...)
+	}
+
+	if turn(verts[0], verts[1], verts[2]) > eps {
+		triangles = append(triangles, verts)
+	}
+
+	return triangles, nil
+}
+
+// inCone returns true if the direction from b to p is inside the polygon at the
+// vertex b between a and c (counter-clockwise winding).
+func inCone(a, b, c, p Vect) bool {
+	if turn(a, b, c) >= 0.0 {
+		return turn(b, p, a) > 0.0 && turn(p, b, c) > 0.0
+	}
+	return !(turn(b, p, c) >= 0.0 && turn(p, b, a) >= 0.0)
+}
+
+// isEar returns true if no other vertex of a polygon is inside the triangle a-b-c,
+// or on its boundary unless strict is true.
+func isEar(verts []Vect, a, b, c Vect, strict bool) bool {
+	for _, p := range verts {
+		if p == a || p == b || p == c {
+			continue
+		}
+
+		t1, t2, t3 := turn(a, b, p), turn(b, c, p), turn(c, a, p)
+		if strict && t1 > 0.0 && t2 > 0.0 && t3 > 0.0 {
+			return false
+		}
+		if !strict && t1 >= 0.0 && t2 >= 0.0 && t3 >= 0.0 {
+			return false
+		}
+	}
+	return true
+}
+
+// edgeIndex returns the index of the vertex a of a polygon followed by b,
+// or -1 if there is no such edge.
+func edgeIndex(poly []Vect, a, b Vect) int {
+	for i := range poly {
+		if poly[i] == a && poly[(i+1)%len(poly)] == b {
+			return i
+		}
+	}
+	return -1
+}
+
+// isConvex returns true if a polygon wound counter-clockwise is convex.
+func isConvex(poly []Vect, eps float64) bool {
+	n := len(poly)
+	for i := range poly {
+		if turn(poly[(i+n-1)%n], poly[i], poly[(i+1)%n]) < -eps {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeConvex merges triangles wound counter-clockwise into convex polygons,
+// removing every diagonal between them whose removal keeps the result convex
+// in a single pass over the diagonals.
+func mergeConvex(triangles [][]Vect, eps float64) [][]Vect {
+	type edge struct {
+		a, b Vect
+	}
+
+	owner := make(map[edge]int)
+	for i, t := range triangles {
+		for k := range t {
+			owner[edge{t[k], t[(k+1)%len(t)]}] = i
+		}
+	}
+
+	var diagonals []edge
+	for i, t := range triangles {
+		for k := range t {
+			e := edge{t[k], t[(k+1)%len(t)]}
+			if j, ok := owner[edge{e.b, e.a}]; ok && j > i {
+				diagonals = append(diagonals, e)
+			}
+		}
+	}
+
+	// the triangles merged into a polygon point at the one holding it
+	polys := append([][]Vect(nil), triangles...)
+	parent := make([]int, len(polys))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	for _, d := range diagonals {
+		i, j := find(owner[d]), find(owner[edge{d.b, d.a}])
+		if i == j {
+			continue
+		}
+		if p := mergeAt(polys[i], polys[j], d.a, d.b, eps); p != nil {
+			polys[i], polys[j] = p, nil
+			parent[j] = i
+		}
+	}
+
+	var pieces [][]Vect
+	for i, p := range polys {
+		if parent[i] == i {
+			pieces = append(pieces, cleanPolygon(p, eps))
+		}
+	}
+	return pieces
+}
+
+// mergeAt merges two polygons wound counter-clockwise along their shared edge a-b
+// if the result is convex. Returns nil if it isn't or the edge isn't shared.
+// Collinear vertices are kept, as they may end other shared edges.
+func mergeAt(p, q []Vect, a, b Vect, eps float64) []Vect {
+	i, j := edgeIndex(p, a, b), edgeIndex(q, b, a)
+	if i < 0 || j < 0 {
+		return nil
+	}
+
+	merged := make([]Vect, 0, len(p)+len(q)-2)
+	for k := 1; k <= len(p); k++ {
+		merged = append(merged, p[(i+k)%len(p)])
+	}
+	for k := 2; k < len(q); k++ {
+		merged = append(merged, q[(j+k)%len(q)])
+	}
+
+	if !isConvex(merged, eps) {
+		return nil
+	}
+	return merged
+}
+
+// reverseVerts reverses the order of vertices in place.
+func reverseVerts(verts []Vect) {
+	for i, j := 0, len(verts)-1; i < j; i, j = i+1, j-1 {
+		verts[i], verts[j] = verts[j], verts[i]
+	}
+}
+
+// rightmostVert returns the index of the vertex with the greatest x coordinate.
+func rightmostVert(verts []Vect) int {
+	m := 0
+	for i, v := range verts {
+		if v.X > verts[m].X {
+			m = i
+		}
+	}
+	return m
+}
+
+// segmentsIntersect returns true if the segments a-b and c-d intersect or touch.
+func segmentsIntersect(a, b, c, d Vect) bool {
+	d1, d2 := turn(c, d, a), turn(c, d, b)
+	d3, d4 := turn(a, b, c), turn(a, b, d)
+
+	if ((d1 > 0.0 && d2 < 0.0) || (d1 < 0.0 && d2 > 0.0)) &&
+		((d3 > 0.0 && d4 < 0.0) || (d3 < 0.0 && d4 > 0.0)) {
+		return true
+	}
+
+	onSegment := func(p, q, r Vect) bool {
+		return math.Min(p.X, q.X) <= r.X && r.X <= math.Max(p.X, q.X) &&
+			math.Min(p.Y, q.Y) <= r.Y && r.Y <= math.Max(p.Y, q.Y)
+	}
+
+	return (0.0 == d1 && onSegment(c, d, a)) || (0.0 == d2 && onSegment(c, d, b)) ||
+		(0.0 == d3 && onSegment(a, b, c)) || (0.0 == d4 && onSegment(a, b, d))
+}
+
+// signedArea returns the area of a polygon, positive for a counter-clockwise winding.
+func signedArea(verts []Vect) float64 {
+	area := 0.0
+	for i, v := range verts {
+		area += v.Cross(verts[(i+1)%len(verts)])
+	}
+	return area / 2.0
+}
+
+// turn returns the cross product of a-b and b-c, positive for a left turn at b.
+func turn(a, b, c Vect) float64 {
+	return b.Sub(a).Cross(c.Sub(b))
+}
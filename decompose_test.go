@@ -0,0 +1,105 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"errors"
+	"github.com/bmizerany/assert"
+	"math"
+	"testing"
+)
+
+// decompositionArea returns the total area of convex pieces checking they are valid.
+func decompositionArea(t *testing.T, pieces [][]Vect) float64 {
+	area := 0.0
+	for _, p := range pieces {
+		assert.Tf(t, PolyValidate(p), "invalid piece %v", p)
+		area += AreaForPoly(p)
+	}
+	return area
+}
+
+func Test_ConvexDecomposition(t *testing.T) {
+	// a U shape, wound counter-clockwise
+	u := []Vect{{0, 0}, {3, 0}, {3, 2}, {2, 2}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}
+
+	pieces, err := ConvexDecomposition(u, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(pieces))
+	assert.T(t, nearlyEqual(4.0, decompositionArea(t, pieces)))
+
+	reverseVerts(u)
+	pieces, err = ConvexDecomposition(u, nil)
+	assert.Equal(t, nil, err)
+	assert.T(t, nearlyEqual(4.0, decompositionArea(t, pieces)))
+}
+
+func Test_ConvexDecompositionScale(t *testing.T) {
+	// the tolerance scales with the polygon, so a tiny U is split like a big one
+	for _, scale := range []float64{1e-6, 1e6} {
+		var u []Vect
+		for _, v := range []Vect{{0, 0}, {3, 0}, {3, 2}, {2, 2}, {2, 1}, {1, 1}, {1, 2}, {0, 2}} {
+			u = append(u, v.Mul(scale))
+		}
+
+		pieces, err := ConvexDecomposition(u, nil)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 3, len(pieces))
+		assert.T(t, math.Abs(decompositionArea(t, pieces)/(4.0*scale*scale)-1.0) < 1e-9)
+	}
+}
+
+func Test_ConvexDecompositionHoles(t *testing.T) {
+	square := []Vect{{0, 0}, {10, 0}, {10, 4}, {0, 4}}
+	holes := [][]Vect{
+		{{1, 1}, {3, 1}, {3, 3}, {1, 3}},
+		{{8, 1}, {6, 1}, {6, 3}, {8, 3}},
+	}
+
+	pieces, err := ConvexDecomposition(square, holes)
+	assert.Equal(t, nil, err)
+	assert.T(t, nearlyEqual(40.0-8.0, decompositionArea(t, pieces)))
+
+	// a star with a hole in its middle
+	var star []Vect
+	for i := 0; i < 10; i++ {
+		r := 2.0 - float64(i%2)
+		star = append(star, VectForAngle(float64(i)*math.Pi/5.0).Mul(r))
+	}
+	hole := []Vect{{-0.3, -0.3}, {0.3, -0.3}, {0.3, 0.3}, {-0.3, 0.3}}
+
+	pieces, err = ConvexDecomposition(star, [][]Vect{hole})
+	assert.Equal(t, nil, err)
+	assert.T(t, math.Abs(math.Abs(AreaForPoly(star))-0.36-decompositionArea(t, pieces)) < 1e-9)
+}
+
+func Test_ConvexDecompositionInvalid(t *testing.T) {
+	_, err := ConvexDecomposition([]Vect{{0, 0}, {1, 1}, {2, 2}}, nil)
+	assert.T(t, errors.Is(err, ErrInvalidArgument), err)
+
+	square := []Vect{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	outside := []Vect{{2, 2}, {3, 2}, {3, 3}, {2, 3}}
+	_, err = ConvexDecomposition(square, [][]Vect{outside})
+	assert.T(t, errors.Is(err, ErrInvalidArgument), err)
+}
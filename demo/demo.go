@@ -63,11 +63,16 @@ func main() {
 
 	Running = true
 
+	stepper := StepperNew(*space, 1.0/20.0)
+	stepper.SetSubsteps(3)
+	last := time.Now()
+
 	for Running && glfw.WindowParam(glfw.Opened) == 1 {
 		drawScene()
-		space.Step(1.0 / 20.0 / 3.0)
-		space.Step(1.0 / 20.0 / 3.0)
-		space.Step(1.0 / 20.0 / 3.0)
+
+		now := time.Now()
+		stepper.Update(now.Sub(last).Seconds())
+		last = now
 	}
 }
 
@@ -0,0 +1,347 @@
+package encoding
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Decoder reads spaces from JSON documents in an input stream.
+type Decoder struct {
+	// UnmarshalUserData converts JSON user data back to a Go value.
+	// It's never called for objects without user data.
+	// If it's nil, json.Unmarshal into interface{} is used.
+	UnmarshalUserData func(data json.RawMessage) (interface{}, error)
+
+	dec *json.Decoder
+}
+
+// userDataSetter is implemented by every object which has user data.
+type userDataSetter interface {
+	SetUserData(interface{})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// Decode reads the next JSON document from the stream and builds a new space out of it.
+func (d *Decoder) Decode() (chipmunk.Space, error) {
+	var doc Document
+
+	if err := d.dec.Decode(&doc); err != nil {
+		return 0, err
+	}
+
+	return d.Space(&doc)
+}
+
+// Space builds a new space out of a document.
+// The document is validated first, so invalid input never reaches Chipmunk.
+func (d *Decoder) Space(doc *Document) (chipmunk.Space, error) {
+	if err := doc.validate(); err != nil {
+		return 0, err
+	}
+
+	s := chipmunk.SpaceNew()
+
+	if bodies, err := d.build(s, doc); err != nil {
+		free(s, bodies)
+		return 0, err
+	}
+
+	return s, nil
+}
+
+// build fills an empty space with the contents of a validated document.
+// It returns the bodies created so far even on error, so they can be freed.
+func (d *Decoder) build(s chipmunk.Space, doc *Document) ([]chipmunk.Body, error) {
+	s.SetGravity(doc.Space.Gravity.cp())
+	s.SetDamping(float64(doc.Space.Damping))
+	s.SetIterations(doc.Space.Iterations)
+	s.SetIdleSpeedThreshold(float64(doc.Space.IdleSpeedThreshold))
+	s.SetSleepTimeThreshold(float64(doc.Space.SleepTimeThreshold))
+	s.SetCollisionSlop(float64(doc.Space.CollisionSlop))
+	s.SetCollisionBias(float64(doc.Space.CollisionBias))
+	s.SetCollisionPersistence(chipmunk.Timestamp(doc.Space.CollisionPersistence))
+	s.SetEnableContactGraph(doc.Space.EnableContactGraph)
+
+	bodies := make([]chipmunk.Body, 0, len(doc.Bodies))
+
+	if err := d.setUserData(s, doc.Space.UserData); err != nil {
+		return bodies, err
+	}
+
+	body := func(i int) chipmunk.Body {
+		if i == StaticBody {
+			return s.StaticBody()
+		}
+		return bodies[i]
+	}
+
+	for _, bd := range doc.Bodies {
+		var b chipmunk.Body
+
+		if bd.Static {
+			b = chipmunk.BodyStaticNew()
+		} else {
+			b = chipmunk.BodyNew(float64(bd.Mass), float64(bd.Moment))
+			b.SetForce(bd.Force.cp())
+			b.SetTorque(float64(bd.Torque))
+		}
+
+		b.SetPosition(bd.Position.cp())
+		b.SetVelocity(bd.Velocity.cp())
+		b.SetAngle(float64(bd.Angle))
+		b.SetAngularVelocity(float64(bd.AngularVelocity))
+		b.SetVelocityLimit(float64(bd.VelocityLimit))
+		b.SetAngularVelocityLimit(float64(bd.AngularVelocityLimit))
+
+		if !bd.Static && !bd.Rogue {
+			s.AddBody(b)
+		}
+
+		bodies = append(bodies, b)
+
+		if err := d.setUserData(b, bd.UserData); err != nil {
+			return bodies, err
+		}
+	}
+
+	for _, sd := range doc.Shapes {
+		var sh chipmunk.Shape
+		b := body(sd.Body)
+
+		switch sd.Type {
+		case "circle":
+			sh = chipmunk.CircleShapeNew(b, float64(sd.Radius), sd.Offset.cpOrOrigin())
+		case "segment":
+			sh = chipmunk.SegmentShapeNew(b, sd.A.cpOrOrigin(), sd.B.cpOrOrigin(), float64(sd.Radius))
+		case "poly":
+			sh = chipmunk.PolyShapeNew2(b, verts(sd.Verts), chipmunk.Origin(), float64(sd.Radius))
+		}
+
+		sh.SetSensor(sd.Sensor)
+		sh.SetElasticity(float64(sd.Elasticity))
+		sh.SetFriction(float64(sd.Friction))
+		sh.SetSurfaceVelocity(sd.SurfaceVelocity.cp())
+		sh.SetCollisionType(chipmunk.CollisionType(sd.CollisionType))
+		sh.SetGroup(chipmunk.Group(sd.Group))
+		if nil != sd.Layers {
+			sh.SetLayers(chipmunk.Layers(*sd.Layers))
+		}
+		s.AddShape(sh)
+
+		if u, ok := sh.(userDataSetter); ok {
+			if err := d.setUserData(u, sd.UserData); err != nil {
+				return bodies, err
+			}
+		}
+	}
+
+	for _, cd := range doc.Constraints {
+		c := constraint(&cd, body(cd.A), body(cd.B))
+		c.SetMaxForce(float64(cd.MaxForce))
+		c.SetErrorBias(float64(cd.ErrorBias))
+		c.SetMaxBias(float64(cd.MaxBias))
+		s.AddConstraint(c)
+
+		if err := d.setUserData(c, cd.UserData); err != nil {
+			return bodies, err
+		}
+	}
+
+	return bodies, nil
+}
+
+// setUserData converts JSON user data using the unmarshaller hook and sets it.
+func (d *Decoder) setUserData(obj userDataSetter, data json.RawMessage) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+
+	if d.UnmarshalUserData != nil {
+		v, err = d.UnmarshalUserData(data)
+	} else {
+		err = json.Unmarshal(data, &v)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	obj.SetUserData(v)
+	return nil
+}
+
+// constraint creates a new constraint described by cd.
+func constraint(cd *Constraint, a, b chipmunk.Body) chipmunk.Constraint {
+	switch cd.Type {
+	case "dampedRotarySpring":
+		return chipmunk.DampedRotarySpringNew(a, b, cd.RestAngle.value(), cd.Stiffness.value(),
+			cd.Damping.value())
+	case "dampedSpring":
+		return chipmunk.DampedSpringNew(a, b, cd.Anchr1.cpOrOrigin(), cd.Anchr2.cpOrOrigin(),
+			cd.RestLength.value(), cd.Stiffness.value(), cd.Damping.value())
+	case "gearJoint":
+		return chipmunk.GearJointNew(a, b, cd.Phase.value(), cd.Ratio.value())
+	case "grooveJoint":
+		return chipmunk.GrooveJointNew(a, b, cd.GrooveA.cpOrOrigin(), cd.GrooveB.cpOrOrigin(),
+			cd.Anchr2.cpOrOrigin())
+	case "pinJoint":
+		c := chipmunk.PinJointNew(a, b, cd.Anchr1.cpOrOrigin(), cd.Anchr2.cpOrOrigin())
+		if cd.Dist != nil {
+			c.SetDist(cd.Dist.value())
+		}
+		return c
+	case "pivotJoint":
+		return chipmunk.PivotJointNew2(a, b, cd.Anchr1.cpOrOrigin(), cd.Anchr2.cpOrOrigin())
+	case "ratchetJoint":
+		c := chipmunk.RatchetJointNew(a, b, cd.Phase.value(), cd.Ratchet.value())
+		c.SetAngle(cd.Angle.value())
+		return c
+	case "rotaryLimitJoint":
+		return chipmunk.RotaryLimitJointNew(a, b, cd.Min.value(), cd.Max.value())
+	case "simpleMotor":
+		return chipmunk.SimpleMotorNew(a, b, cd.Rate.value())
+	case "slideJoint":
+		return chipmunk.SlideJointNew(a, b, cd.Anchr1.cpOrOrigin(), cd.Anchr2.cpOrOrigin(),
+			cd.Min.value(), cd.Max.value())
+	}
+
+	panic("unknown constraint type in constraint")
+}
+
+// free removes and frees everything in the space, the given bodies and the space itself.
+func free(s chipmunk.Space, bodies []chipmunk.Body) {
+	var (
+		shapes      []chipmunk.Shape
+		constraints []chipmunk.Constraint
+	)
+
+	s.EachShape(func(sh chipmunk.Shape) {
+		shapes = append(shapes, sh)
+	})
+
+	s.EachConstraint(func(c chipmunk.Constraint) {
+		constraints = append(constraints, c)
+	})
+
+	for _, c := range constraints {
+		s.RemoveConstraint(c)
+		c.Free()
+	}
+
+	for _, sh := range shapes {
+		s.RemoveShape(sh)
+		sh.Free()
+	}
+
+	for _, b := range bodies {
+		if s.Contains(b) {
+			s.RemoveBody(b)
+		}
+		b.Free()
+	}
+
+	s.Free()
+}
+
+// verts converts a vertex list to chipmunk vectors.
+func verts(vs []Vect) []chipmunk.Vect {
+	r := make([]chipmunk.Vect, len(vs))
+	for i, v := range vs {
+		r[i] = v.cp()
+	}
+	return r
+}
+
+// validate reports the first problem in the document which would make Chipmunk abort.
+func (doc *Document) validate() error {
+	if doc.Version != Version {
+		return fmt.Errorf("chipmunk/encoding: unsupported document version %d", doc.Version)
+	}
+
+	for i, bd := range doc.Bodies {
+		if !bd.Static && (bd.Mass <= 0.0 || bd.Moment <= 0.0) {
+			return fmt.Errorf("chipmunk/encoding: body %d: mass and moment must be positive", i)
+		}
+	}
+
+	checkBody := func(what string, i, b int) error {
+		if b != StaticBody && (b < 0 || b >= len(doc.Bodies)) {
+			return fmt.Errorf("chipmunk/encoding: %s %d: invalid body reference %d", what, i, b)
+		}
+		return nil
+	}
+
+	for i, sd := range doc.Shapes {
+		if err := checkBody("shape", i, sd.Body); err != nil {
+			return err
+		}
+
+		switch sd.Type {
+		case "circle", "segment":
+		case "poly":
+			if len(sd.Verts) < 3 || !chipmunk.PolyValidate(verts(sd.Verts)) {
+				return fmt.Errorf("chipmunk/encoding: shape %d: polygon must be convex and clockwise", i)
+			}
+		default:
+			return fmt.Errorf("chipmunk/encoding: shape %d: unknown type %q", i, sd.Type)
+		}
+	}
+
+	for i, cd := range doc.Constraints {
+		if err := checkBody("constraint", i, cd.A); err != nil {
+			return err
+		}
+
+		if err := checkBody("constraint", i, cd.B); err != nil {
+			return err
+		}
+
+		switch cd.Type {
+		case "dampedRotarySpring", "dampedSpring", "gearJoint", "grooveJoint", "pinJoint",
+			"pivotJoint", "ratchetJoint", "rotaryLimitJoint", "simpleMotor", "slideJoint":
+		default:
+			return fmt.Errorf("chipmunk/encoding: constraint %d: unknown type %q", i, cd.Type)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,307 @@
+package encoding
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Encoder writes spaces as JSON documents to an output stream.
+type Encoder struct {
+	// MarshalUserData converts user data of the space and its objects to JSON.
+	// It's never called for nil user data. If it's nil, json.Marshal is used.
+	MarshalUserData func(data interface{}) (json.RawMessage, error)
+
+	enc *json.Encoder
+}
+
+// userDataHolder is implemented by every object which has user data.
+type userDataHolder interface {
+	UserData() interface{}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: json.NewEncoder(w)}
+}
+
+// Document returns a document describing the space and everything in it.
+func (e *Encoder) Document(s chipmunk.Space) (*Document, error) {
+	var err error
+
+	doc := &Document{
+		Version: Version,
+		Space: Space{
+			Gravity:              vect(s.Gravity()),
+			Damping:              Float(s.Damping()),
+			Iterations:           s.Iterations(),
+			IdleSpeedThreshold:   Float(s.IdleSpeedThreshold()),
+			SleepTimeThreshold:   Float(s.SleepTimeThreshold()),
+			CollisionSlop:        Float(s.CollisionSlop()),
+			CollisionBias:        Float(s.CollisionBias()),
+			CollisionPersistence: uint(s.CollisionPersistence()),
+			EnableContactGraph:   s.EnableContactGraph(),
+		},
+	}
+
+	if doc.Space.UserData, err = e.userData(s.UserData()); err != nil {
+		return nil, err
+	}
+
+	bodies := map[chipmunk.Body]int{s.StaticBody(): StaticBody}
+	bodyRef := func(b chipmunk.Body) (int, error) {
+		if i, ok := bodies[b]; ok {
+			return i, nil
+		}
+
+		bd, err := e.body(s, b)
+		if err != nil {
+			return 0, err
+		}
+
+		bodies[b] = len(doc.Bodies)
+		doc.Bodies = append(doc.Bodies, bd)
+		return bodies[b], nil
+	}
+
+	s.EachBody(func(b chipmunk.Body) {
+		if err == nil {
+			_, err = bodyRef(b)
+		}
+	})
+
+	s.EachShape(func(sh chipmunk.Shape) {
+		if err != nil {
+			return
+		}
+
+		var sd Shape
+		if sd, err = e.shape(sh); err == nil {
+			sd.Body, err = bodyRef(sh.Body())
+			doc.Shapes = append(doc.Shapes, sd)
+		}
+	})
+
+	s.EachConstraint(func(c chipmunk.Constraint) {
+		if err != nil {
+			return
+		}
+
+		var cd Constraint
+		if cd, err = e.constraint(c); err != nil {
+			return
+		}
+
+		if cd.A, err = bodyRef(c.A()); err != nil {
+			return
+		}
+
+		cd.B, err = bodyRef(c.B())
+		doc.Constraints = append(doc.Constraints, cd)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// Encode writes the JSON document of the space to the stream.
+func (e *Encoder) Encode(s chipmunk.Space) error {
+	doc, err := e.Document(s)
+	if err != nil {
+		return err
+	}
+
+	return e.enc.Encode(doc)
+}
+
+// SetIndent instructs the encoder to indent the output, see json.Encoder.SetIndent.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.enc.SetIndent(prefix, indent)
+}
+
+// body returns a description of a body.
+func (e *Encoder) body(s chipmunk.Space, b chipmunk.Body) (Body, error) {
+	bd := Body{
+		Static:               b.IsStatic(),
+		Rogue:                !s.Contains(b),
+		Position:             vect(b.Position()),
+		Velocity:             vect(b.Velocity()),
+		Force:                vect(b.Force()),
+		Angle:                Float(b.Angle()),
+		AngularVelocity:      Float(b.AngularVelocity()),
+		Torque:               Float(b.Torque()),
+		VelocityLimit:        Float(b.VelocityLimit()),
+		AngularVelocityLimit: Float(b.AngularVelocityLimit()),
+	}
+
+	if !bd.Static {
+		bd.Mass = Float(b.Mass())
+		bd.Moment = Float(b.Moment())
+	}
+
+	var err error
+	bd.UserData, err = e.userData(b.UserData())
+	return bd, err
+}
+
+// shape returns a description of a shape, except for the body reference.
+func (e *Encoder) shape(sh chipmunk.Shape) (Shape, error) {
+	sd := Shape{
+		Sensor:          sh.Sensor(),
+		Elasticity:      Float(sh.Elasticity()),
+		Friction:        Float(sh.Friction()),
+		SurfaceVelocity: vect(sh.SurfaceVelocity()),
+		CollisionType:   uint64(sh.CollisionType()),
+		Group:           uint64(sh.Group()),
+	}
+
+	// shapes are in all layers unless the document says otherwise
+	if l := sh.Layers(); l != chipmunk.AllLayers {
+		layers := uint(l)
+		sd.Layers = &layers
+	}
+
+	switch sh := sh.(type) {
+	case chipmunk.CircleShape:
+		sd.Type = "circle"
+		sd.Radius = Float(sh.Radius())
+		sd.Offset = vectp(sh.Offset())
+
+	case chipmunk.SegmentShape:
+		sd.Type = "segment"
+		sd.Radius = Float(sh.Radius())
+		sd.A = vectp(sh.A())
+		sd.B = vectp(sh.B())
+
+	case chipmunk.PolyShape:
+		sd.Type = "poly"
+		sd.Radius = Float(sh.Radius())
+		sd.Verts = make([]Vect, sh.NumVerts())
+		for i := range sd.Verts {
+			sd.Verts[i] = vect(sh.VertLocal(i))
+		}
+	}
+
+	var err error
+	if u, ok := sh.(userDataHolder); ok {
+		sd.UserData, err = e.userData(u.UserData())
+	}
+	return sd, err
+}
+
+// constraint returns a description of a constraint, except for the body references.
+func (e *Encoder) constraint(c chipmunk.Constraint) (Constraint, error) {
+	cd := Constraint{
+		MaxForce:  Float(c.MaxForce()),
+		ErrorBias: Float(c.ErrorBias()),
+		MaxBias:   Float(c.MaxBias()),
+	}
+
+	switch c := c.(type) {
+	case chipmunk.DampedRotarySpring:
+		cd.Type = "dampedRotarySpring"
+		cd.RestAngle = float(c.RestAngle())
+		cd.Stiffness = float(c.Stiffness())
+		cd.Damping = float(c.Damping())
+
+	case chipmunk.DampedSpring:
+		cd.Type = "dampedSpring"
+		cd.Anchr1 = vectp(c.Anchr1())
+		cd.Anchr2 = vectp(c.Anchr2())
+		cd.RestLength = float(c.RestLength())
+		cd.Stiffness = float(c.Stiffness())
+		cd.Damping = float(c.Damping())
+
+	case chipmunk.GearJoint:
+		cd.Type = "gearJoint"
+		cd.Phase = float(c.Phase())
+		cd.Ratio = float(c.Ratio())
+
+	case chipmunk.GrooveJoint:
+		cd.Type = "grooveJoint"
+		cd.GrooveA = vectp(c.GrooveA())
+		cd.GrooveB = vectp(c.GrooveB())
+		cd.Anchr2 = vectp(c.Anchr2())
+
+	case chipmunk.PinJoint:
+		cd.Type = "pinJoint"
+		cd.Anchr1 = vectp(c.Anchr1())
+		cd.Anchr2 = vectp(c.Anchr2())
+		cd.Dist = float(c.Dist())
+
+	case chipmunk.PivotJoint:
+		cd.Type = "pivotJoint"
+		cd.Anchr1 = vectp(c.Anchr1())
+		cd.Anchr2 = vectp(c.Anchr2())
+
+	case chipmunk.RatchetJoint:
+		cd.Type = "ratchetJoint"
+		cd.Angle = float(c.Angle())
+		cd.Phase = float(c.Phase())
+		cd.Ratchet = float(c.Ratchet())
+
+	case chipmunk.RotaryLimitJoint:
+		cd.Type = "rotaryLimitJoint"
+		cd.Min = float(c.Min())
+		cd.Max = float(c.Max())
+
+	case chipmunk.SimpleMotor:
+		cd.Type = "simpleMotor"
+		cd.Rate = float(c.Rate())
+
+	case chipmunk.SlideJoint:
+		cd.Type = "slideJoint"
+		cd.Anchr1 = vectp(c.Anchr1())
+		cd.Anchr2 = vectp(c.Anchr2())
+		cd.Min = float(c.Min())
+		cd.Max = float(c.Max())
+	}
+
+	var err error
+	cd.UserData, err = e.userData(c.UserData())
+	return cd, err
+}
+
+// userData converts user data to JSON using the marshaller hook.
+func (e *Encoder) userData(data interface{}) (json.RawMessage, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	if e.MarshalUserData != nil {
+		return e.MarshalUserData(data)
+	}
+
+	return json.Marshal(data)
+}
@@ -0,0 +1,223 @@
+package encoding
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/ianremmler/chipmunk"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Version is the version of the document format written by Encoder.
+const Version = 1
+
+// StaticBody is a body reference to the dedicated static body of the space.
+const StaticBody = -1
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Document is a JSON representation of a space and everything in it.
+type Document struct {
+	Version     int          `json:"version"`
+	Space       Space        `json:"space"`
+	Bodies      []Body       `json:"bodies,omitempty"`
+	Shapes      []Shape      `json:"shapes,omitempty"`
+	Constraints []Constraint `json:"constraints,omitempty"`
+}
+
+// Space is a JSON representation of the space simulation parameters.
+type Space struct {
+	Gravity              Vect            `json:"gravity"`
+	Damping              Float           `json:"damping"`
+	Iterations           int             `json:"iterations"`
+	IdleSpeedThreshold   Float           `json:"idleSpeedThreshold"`
+	SleepTimeThreshold   Float           `json:"sleepTimeThreshold"`
+	CollisionSlop        Float           `json:"collisionSlop"`
+	CollisionBias        Float           `json:"collisionBias"`
+	CollisionPersistence uint            `json:"collisionPersistence"`
+	EnableContactGraph   bool            `json:"enableContactGraph,omitempty"`
+	UserData             json.RawMessage `json:"userData,omitempty"`
+}
+
+// Body is a JSON representation of a rigid body.
+// Bodies are referenced from shapes and constraints by their index in Document.Bodies.
+type Body struct {
+	// Static is true for static bodies, they are never added to the space.
+	Static bool `json:"static,omitempty"`
+	// Rogue is true for bodies which are not added to the space.
+	Rogue                bool            `json:"rogue,omitempty"`
+	Mass                 Float           `json:"mass,omitempty"`
+	Moment               Float           `json:"moment,omitempty"`
+	Position             Vect            `json:"position"`
+	Velocity             Vect            `json:"velocity"`
+	Force                Vect            `json:"force"`
+	Angle                Float           `json:"angle"`
+	AngularVelocity      Float           `json:"angularVelocity"`
+	Torque               Float           `json:"torque"`
+	VelocityLimit        Float           `json:"velocityLimit"`
+	AngularVelocityLimit Float           `json:"angularVelocityLimit"`
+	UserData             json.RawMessage `json:"userData,omitempty"`
+}
+
+// Shape is a JSON representation of a collision shape.
+type Shape struct {
+	// Type is one of "circle", "segment" or "poly".
+	Type string `json:"type"`
+	Body int    `json:"body"`
+
+	Radius Float  `json:"radius"`
+	Offset *Vect  `json:"offset,omitempty"`
+	A      *Vect  `json:"a,omitempty"`
+	B      *Vect  `json:"b,omitempty"`
+	Verts  []Vect `json:"verts,omitempty"`
+
+	Sensor          bool            `json:"sensor,omitempty"`
+	Elasticity      Float           `json:"elasticity"`
+	Friction        Float           `json:"friction"`
+	SurfaceVelocity Vect            `json:"surfaceVelocity"`
+	CollisionType   uint64          `json:"collisionType,omitempty"`
+	Group           uint64          `json:"group,omitempty"`
+	Layers          *uint           `json:"layers,omitempty"`
+	UserData        json.RawMessage `json:"userData,omitempty"`
+}
+
+// Constraint is a JSON representation of a constraint.
+// Only the parameters of the given type are present.
+type Constraint struct {
+	// Type is one of "dampedRotarySpring", "dampedSpring", "gearJoint", "grooveJoint",
+	// "pinJoint", "pivotJoint", "ratchetJoint", "rotaryLimitJoint", "simpleMotor" or "slideJoint".
+	Type string `json:"type"`
+	A    int    `json:"a"`
+	B    int    `json:"b"`
+
+	MaxForce  Float `json:"maxForce"`
+	ErrorBias Float `json:"errorBias"`
+	MaxBias   Float `json:"maxBias"`
+
+	Anchr1     *Vect  `json:"anchr1,omitempty"`
+	Anchr2     *Vect  `json:"anchr2,omitempty"`
+	GrooveA    *Vect  `json:"grooveA,omitempty"`
+	GrooveB    *Vect  `json:"grooveB,omitempty"`
+	Dist       *Float `json:"dist,omitempty"`
+	Min        *Float `json:"min,omitempty"`
+	Max        *Float `json:"max,omitempty"`
+	RestLength *Float `json:"restLength,omitempty"`
+	RestAngle  *Float `json:"restAngle,omitempty"`
+	Stiffness  *Float `json:"stiffness,omitempty"`
+	Damping    *Float `json:"damping,omitempty"`
+	Phase      *Float `json:"phase,omitempty"`
+	Ratio      *Float `json:"ratio,omitempty"`
+	Ratchet    *Float `json:"ratchet,omitempty"`
+	Angle      *Float `json:"angle,omitempty"`
+	Rate       *Float `json:"rate,omitempty"`
+
+	UserData json.RawMessage `json:"userData,omitempty"`
+}
+
+// Vect is a JSON representation of a 2D vector.
+type Vect struct {
+	X Float `json:"x"`
+	Y Float `json:"y"`
+}
+
+// Float is a float64 which encodes infinities as "Infinity" and "-Infinity" strings.
+type Float float64
+
+////////////////////////////////////////////////////////////////////////////////
+
+// MarshalJSON implements json.Marshaler.
+func (f Float) MarshalJSON() ([]byte, error) {
+	switch {
+	case math.IsInf(float64(f), 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(float64(f), -1):
+		return []byte(`"-Infinity"`), nil
+	}
+
+	return json.Marshal(float64(f))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (f *Float) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case `"Infinity"`:
+		*f = Float(math.Inf(1))
+		return nil
+	case `"-Infinity"`:
+		*f = Float(math.Inf(-1))
+		return nil
+	}
+
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("chipmunk/encoding: invalid number %s", data)
+	}
+
+	*f = Float(v)
+	return nil
+}
+
+// float returns a pointer to the Float value of f.
+func float(f float64) *Float {
+	v := Float(f)
+	return &v
+}
+
+// vect converts chipmunk.Vect to Vect.
+func vect(v chipmunk.Vect) Vect {
+	return Vect{Float(v.X), Float(v.Y)}
+}
+
+// vectp returns a pointer to the Vect value of v.
+func vectp(v chipmunk.Vect) *Vect {
+	r := vect(v)
+	return &r
+}
+
+// cp converts Vect to chipmunk.Vect.
+func (v Vect) cp() chipmunk.Vect {
+	return chipmunk.VectNew(float64(v.X), float64(v.Y))
+}
+
+// cpOrOrigin converts an optional Vect to chipmunk.Vect, nil is the origin.
+func (v *Vect) cpOrOrigin() chipmunk.Vect {
+	if v == nil {
+		return chipmunk.Origin()
+	}
+
+	return v.cp()
+}
+
+// value returns the value of an optional Float, nil is zero.
+func (f *Float) value() float64 {
+	if f == nil {
+		return 0.0
+	}
+
+	return float64(*f)
+}
@@ -0,0 +1,190 @@
+package encoding
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/ianremmler/chipmunk"
+)
+
+func encode(t *testing.T, s chipmunk.Space) string {
+	var buf bytes.Buffer
+
+	err := NewEncoder(&buf).Encode(s)
+	assert.Equal(t, nil, err)
+
+	return buf.String()
+}
+
+// sortedDocument parses a document and sorts its shapes,
+// as shape iteration order depends on shape IDs.
+func sortedDocument(t *testing.T, data string) Document {
+	var doc Document
+	assert.Equal(t, nil, json.Unmarshal([]byte(data), &doc))
+
+	key := func(i int) string {
+		b, _ := json.Marshal(doc.Shapes[i])
+		return string(b)
+	}
+	sort.Slice(doc.Shapes, func(i, j int) bool {
+		return key(i) < key(j)
+	})
+
+	return doc
+}
+
+func freeAll(s chipmunk.Space) {
+	var bodies []chipmunk.Body
+
+	s.EachBody(func(b chipmunk.Body) {
+		bodies = append(bodies, b)
+	})
+
+	free(s, bodies)
+}
+
+func Test_EncodingRoundTrip(t *testing.T) {
+	s := chipmunk.SpaceNew()
+	s.SetGravity(chipmunk.VectNew(0.0, -100.0))
+	s.SetIterations(20)
+	s.SetUserData("level 1")
+
+	ground := chipmunk.SegmentShapeNew(s.StaticBody(), chipmunk.VectNew(-10, 0), chipmunk.VectNew(10, 0), 0.5)
+	ground.SetFriction(1.0)
+	s.AddShape(ground)
+
+	b1 := s.AddBody(chipmunk.BodyNew(1.0, chipmunk.MomentForCircle(1.0, 0.0, 1.0, chipmunk.Origin())))
+	b1.SetPosition(chipmunk.VectNew(0.0, 5.0))
+	b1.SetUserData(map[string]interface{}{"name": "ball"})
+	ball := chipmunk.CircleShapeNew(b1, 1.0, chipmunk.VectNew(0.5, 0.0))
+	ball.SetCollisionType(3)
+	s.AddShape(ball)
+
+	b2 := s.AddBody(chipmunk.BodyNew(2.0, chipmunk.MomentForBox(2.0, 2.0, 1.0)))
+	b2.SetPosition(chipmunk.VectNew(3.0, 5.0))
+	box := chipmunk.BoxShapeNew(b2, 2.0, 1.0)
+	box.SetLayers(chipmunk.Layers(4))
+	s.AddShape(box)
+
+	s.AddConstraint(chipmunk.DampedRotarySpringNew(b1, b2, 0.5, 10.0, 1.0))
+	s.AddConstraint(chipmunk.DampedSpringNew(b1, b2, chipmunk.Origin(), chipmunk.Origin(), 3.0, 10.0, 1.0))
+	s.AddConstraint(chipmunk.GearJointNew(b1, b2, 0.0, 2.0))
+	s.AddConstraint(chipmunk.GrooveJointNew(b1, b2, chipmunk.VectNew(-1, 0), chipmunk.VectNew(1, 0), chipmunk.Origin()))
+	s.AddConstraint(chipmunk.PinJointNew(b1, b2, chipmunk.Origin(), chipmunk.Origin()))
+	s.AddConstraint(chipmunk.PivotJointNew2(s.StaticBody(), b1, chipmunk.VectNew(0.0, 5.0), chipmunk.Origin()))
+	s.AddConstraint(chipmunk.RatchetJointNew(b1, b2, 0.0, math.Pi/4.0))
+	s.AddConstraint(chipmunk.RotaryLimitJointNew(b1, b2, -1.0, 1.0))
+	s.AddConstraint(chipmunk.SimpleMotorNew(b1, b2, 2.0))
+	s.AddConstraint(chipmunk.SlideJointNew(b1, b2, chipmunk.Origin(), chipmunk.Origin(), 1.0, 4.0))
+
+	out := encode(t, s)
+
+	s2, err := NewDecoder(strings.NewReader(out)).Decode()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "level 1", s2.UserData())
+
+	doc, doc2 := sortedDocument(t, out), sortedDocument(t, encode(t, s2))
+	assert.Equal(t, doc, doc2)
+	assert.Equal(t, 2, len(doc.Bodies))
+	assert.Equal(t, 3, len(doc.Shapes))
+	assert.Equal(t, 10, len(doc.Constraints))
+	assert.Equal(t, Float(math.Inf(1)), doc.Space.SleepTimeThreshold)
+
+	freeAll(s)
+	freeAll(s2)
+}
+
+func Test_EncodingUserDataHooks(t *testing.T) {
+	type tag struct {
+		name string
+	}
+
+	s := chipmunk.SpaceNew()
+	b := s.AddBody(chipmunk.BodyNew(1.0, 1.0))
+	b.SetUserData(&tag{"player"})
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.MarshalUserData = func(data interface{}) (json.RawMessage, error) {
+		return json.Marshal(data.(*tag).name)
+	}
+	assert.Equal(t, nil, enc.Encode(s))
+
+	dec := NewDecoder(&buf)
+	dec.UnmarshalUserData = func(data json.RawMessage) (interface{}, error) {
+		var name string
+		err := json.Unmarshal(data, &name)
+		return &tag{name}, err
+	}
+	s2, err := dec.Decode()
+	assert.Equal(t, nil, err)
+
+	s2.EachBody(func(b chipmunk.Body) {
+		assert.Equal(t, "player", b.UserData().(*tag).name)
+	})
+
+	freeAll(s)
+	freeAll(s2)
+}
+
+func Test_EncodingInvalidDocument(t *testing.T) {
+	docs := []string{
+		`{"version":2}`,
+		`{"version":1,"bodies":[{"mass":0,"moment":1}]}`,
+		`{"version":1,"shapes":[{"type":"circle","body":3}]}`,
+		`{"version":1,"shapes":[{"type":"blob","body":-1}]}`,
+		`{"version":1,"shapes":[{"type":"poly","body":-1,"verts":[{"x":0,"y":0},{"x":1,"y":0},{"x":0,"y":1}]}]}`,
+		`{"version":1,"constraints":[{"type":"weldJoint","a":-1,"b":-1}]}`,
+	}
+
+	for _, doc := range docs {
+		_, err := NewDecoder(strings.NewReader(doc)).Decode()
+		assert.NotEqual(t, nil, err)
+	}
+}
+
+func Test_EncodingDefaultLayers(t *testing.T) {
+	doc := `{"version":1,"shapes":[{"type":"circle","body":-1,"radius":1}]}`
+
+	s, err := NewDecoder(strings.NewReader(doc)).Decode()
+	assert.Equal(t, nil, err)
+
+	var layers []chipmunk.Layers
+	s.EachShape(func(sh chipmunk.Shape) {
+		layers = append(layers, sh.Layers())
+	})
+	assert.Equal(t, []chipmunk.Layers{chipmunk.AllLayers}, layers)
+
+	out := encode(t, s)
+	assert.T(t, !strings.Contains(out, `"layers"`))
+
+	freeAll(s)
+}
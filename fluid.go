@@ -0,0 +1,182 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Fluid is a sensor shape filled with fluid, e.g. water. Each step, every polygon
+// and circle shape touching it is pushed up by buoyancy and slowed down by drag,
+// in proportion to its area below the fluid surface.
+//
+// The surface is horizontal, at the top of the bounding box of the fluid shape,
+// and gravity is expected to point down.
+type Fluid struct {
+	space     Space
+	shape     Shape
+	density   float64
+	viscosity float64
+}
+
+// fluidFilter is the collision filter of a fluid.
+type fluidFilter Fluid
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Density returns the density of the fluid.
+func (f *Fluid) Density() float64 {
+	return f.density
+}
+
+// FluidNew makes a shape in a space a fluid of a density and a viscosity, the
+// drag coefficient. The shape becomes a sensor, it needs a collision type of its
+// own, which may be shared with other fluids.
+func FluidNew(s Space, sh Shape, density, viscosity float64) *Fluid {
+	f := &Fluid{space: s, shape: sh, density: density, viscosity: viscosity}
+
+	sh.SetSensor(true)
+	s.AddCollisionFilter(sh.CollisionType(), AnyCollisionType, (*fluidFilter)(f))
+	return f
+}
+
+// Free stops applying the fluid forces. The shape is left in the space.
+func (f *Fluid) Free() {
+	f.space.RemoveCollisionFilter(f.shape.CollisionType(), AnyCollisionType, (*fluidFilter)(f))
+}
+
+// SetDensity sets the density of the fluid.
+func (f *Fluid) SetDensity(density float64) {
+	f.density = density
+}
+
+// SetViscosity sets the viscosity of the fluid, the drag coefficient.
+func (f *Fluid) SetViscosity(viscosity float64) {
+	f.viscosity = viscosity
+}
+
+// Shape returns the sensor shape of the fluid.
+func (f *Fluid) Shape() Shape {
+	return f.shape
+}
+
+// Viscosity returns the viscosity of the fluid, the drag coefficient.
+func (f *Fluid) Viscosity() float64 {
+	return f.viscosity
+}
+
+// PreSolve applies buoyancy and drag to the body of a shape in the fluid.
+func (ff *fluidFilter) PreSolve(s Space, arb Arbiter) bool {
+	f := (*Fluid)(ff)
+	a, other := arb.Shapes()
+	if a != f.shape {
+		return true
+	}
+
+	level := f.shape.BB().t
+	var area, moment float64
+	var centroid Vect
+
+	switch sh := other.(type) {
+	case PolyShape:
+		verts := submergedPoly(sh.VertsWorld(), level)
+		if len(verts) < 3 {
+			return true
+		}
+		area, centroid = AreaForPoly(verts), CentroidForPoly(verts)
+		moment = MomentForPoly(f.viscosity*f.density*area, verts, sh.Body().Position().Neg())
+	case CircleShape:
+		area, centroid = submergedCircle(sh.Center(), sh.Radius(), level)
+		moment = MomentForCircle(f.viscosity*f.density*area, 0.0, sh.Radius(),
+			centroid.Sub(sh.Body().Position()))
+	default:
+		return true
+	}
+
+	b := other.Body()
+	if !(area > 0.0) || math.IsInf(b.Mass(), 1) {
+		return true
+	}
+
+	r := centroid.Sub(b.Position())
+	dt := s.CurrentTimeStep()
+
+	// buoyancy
+	b.ApplyImpulse(s.Gravity().Mul(-area*f.density*dt), r)
+
+	// linear drag
+	v := b.Velocity().Add(VectNew(-r.Y, r.X).Mul(b.AngularVelocity()))
+	if speed := v.Length(); speed > 0.0 {
+		n := v.Div(speed)
+		rn := r.Cross(n)
+		k := 1.0/b.Mass() + rn*rn/b.Moment()
+
+		damping := area * f.viscosity * f.density
+		b.ApplyImpulse(v.Mul((math.Exp(-damping*dt*k)-1.0)/k), r)
+	}
+
+	// angular drag
+	b.SetAngularVelocity(b.AngularVelocity() * math.Exp(-moment*dt/b.Moment()))
+
+	return true
+}
+
+// submergedCircle returns the area and the centroid of the part of a circle
+// below a level.
+func submergedCircle(center Vect, radius, level float64) (float64, Vect) {
+	// distance of the center above the level
+	d := center.Y - level
+	switch {
+	case d >= radius:
+		return 0.0, center
+	case d <= -radius:
+		return math.Pi * radius * radius, center
+	}
+
+	h := radius*radius - d*d
+	area := radius*radius*math.Acos(d/radius) - d*math.Sqrt(h)
+	return area, center.Sub(VectNew(0.0, 2.0*h*math.Sqrt(h)/(3.0*area)))
+}
+
+// submergedPoly clips a convex polygon to its part below a level.
+func submergedPoly(verts []Vect, level float64) []Vect {
+	clipped := make([]Vect, 0, len(verts)+1)
+
+	for i, j := 0, len(verts)-1; i < len(verts); j, i = i, i+1 {
+		a, b := verts[j], verts[i]
+		if a.Y < level {
+			clipped = append(clipped, a)
+		}
+
+		al, bl := a.Y-level, b.Y-level
+		if al*bl < 0.0 {
+			t := math.Abs(al) / (math.Abs(al) + math.Abs(bl))
+			clipped = append(clipped, a.Add(b.Sub(a).Mul(t)))
+		}
+	}
+
+	return clipped
+}
@@ -0,0 +1,124 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"math"
+	"testing"
+)
+
+func Test_SubmergedCircle(t *testing.T) {
+	area, centroid := submergedCircle(VectNew(1.0, 5.0), 2.0, 0.0)
+	assert.Equal(t, 0.0, area)
+
+	area, centroid = submergedCircle(VectNew(1.0, -5.0), 2.0, 0.0)
+	assert.Equal(t, 4.0*math.Pi, area)
+	assert.Equal(t, VectNew(1.0, -5.0), centroid)
+
+	area, centroid = submergedCircle(VectNew(1.0, 0.0), 2.0, 0.0)
+	assert.T(t, nearlyEqual(2.0*math.Pi, area))
+	assert.T(t, nearlyEqual(-8.0/(3.0*math.Pi), centroid.Y))
+
+	// the parts above and below the level make up the circle
+	above, _ := submergedCircle(VectNew(0.0, -0.5), 2.0, 0.0)
+	below, _ := submergedCircle(VectNew(0.0, 0.5), 2.0, 0.0)
+	assert.T(t, nearlyEqual(4.0*math.Pi, above+below))
+}
+
+func Test_SubmergedPoly(t *testing.T) {
+	square := []Vect{VectNew(-1.0, -1.0), VectNew(-1.0, 1.0), VectNew(1.0, 1.0), VectNew(1.0, -1.0)}
+
+	assert.Equal(t, 0, len(submergedPoly(square, -2.0)))
+	assert.Equal(t, 4.0, AreaForPoly(submergedPoly(square, 2.0)))
+
+	verts := submergedPoly(square, 0.5)
+	assert.T(t, nearlyEqual(3.0, AreaForPoly(verts)))
+	assert.T(t, nearlyEqual(-0.25, CentroidForPoly(verts).Y))
+}
+
+func Test_FluidBuoyancy(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	water := s.AddShape(BoxShapeNew2(s.StaticBody(), BBNew(-10.0, -20.0, 10.0, 0.0)))
+	water.SetCollisionType(3)
+	f := FluidNew(s, water, 1.0, 2.0)
+
+	// half as dense as the water
+	light := s.AddBody(BodyNew(2.0, MomentForBox(2.0, 2.0, 2.0)))
+	light.SetPosition(VectNew(-5.0, 3.0))
+	s.AddShape(BoxShapeNew(light, 2.0, 2.0))
+
+	ball := s.AddBody(BodyNew(math.Pi/2.0, MomentForCircle(math.Pi/2.0, 0.0, 1.0, Origin())))
+	ball.SetPosition(VectNew(0.0, 3.0))
+	s.AddShape(CircleShapeNew(ball, 1.0, Origin()))
+
+	// twice as dense as the water
+	heavy := s.AddBody(BodyNew(8.0, MomentForBox(8.0, 2.0, 2.0)))
+	heavy.SetPosition(VectNew(5.0, 3.0))
+	s.AddShape(BoxShapeNew(heavy, 2.0, 2.0))
+
+	for i := 0; i < 600; i++ {
+		s.Step(1.0 / 60.0)
+	}
+
+	for _, b := range []Body{light, ball} {
+		assert.Tf(t, math.Abs(b.Position().Y) < 0.1, "body doesn't float: %v", b.Position())
+		assert.Tf(t, b.Velocity().Length() < 0.1, "body doesn't come to rest: %v", b.Velocity())
+	}
+	assert.Tf(t, heavy.Position().Y < -10.0, "body doesn't sink: %v", heavy.Position())
+
+	f.Free()
+	s.Free()
+}
+
+func Test_FluidViscosity(t *testing.T) {
+	s := SpaceNew()
+
+	water := s.AddShape(BoxShapeNew2(s.StaticBody(), BBNew(-10.0, -20.0, 10.0, 0.0)))
+	water.SetCollisionType(3)
+	f := FluidNew(s, water, 1.0, 2.0)
+
+	b := s.AddBody(BodyNew(1.0, MomentForBox(1.0, 1.0, 1.0)))
+	b.SetPosition(VectNew(0.0, -5.0))
+	b.SetVelocity(VectNew(5.0, 0.0))
+	b.SetAngularVelocity(5.0)
+	s.AddShape(BoxShapeNew(b, 1.0, 1.0))
+
+	for i := 0; i < 60; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, b.Velocity().Length() < 1.0, "body isn't slowed down: %v", b.Velocity())
+	assert.Tf(t, math.Abs(b.AngularVelocity()) < 1.0, "body isn't slowed down: %v", b.AngularVelocity())
+
+	// without viscosity, nothing slows it down
+	f.SetViscosity(0.0)
+	b.SetVelocity(VectNew(5.0, 0.0))
+	s.Step(1.0 / 60.0)
+	assert.Equal(t, VectNew(5.0, 0.0), b.Velocity())
+
+	f.Free()
+	s.Free()
+}
@@ -0,0 +1,68 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// #include <stdint.h>
+import "C"
+
+import (
+	"runtime/cgo"
+	"sync/atomic"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// liveHandles is the number of handles currently registered.
+var liveHandles int64
+
+////////////////////////////////////////////////////////////////////////////////
+
+// handleNew registers a Go value which is going to be referenced from C
+// and returns its handle. The value is kept alive until handleDelete is called.
+func handleNew(v interface{}) cgo.Handle {
+	atomic.AddInt64(&liveHandles, 1)
+	return cgo.NewHandle(v)
+}
+
+// handleDelete releases a handle returned by handleNew.
+func handleDelete(h cgo.Handle) {
+	h.Delete()
+	atomic.AddInt64(&liveHandles, -1)
+}
+
+// handleToC converts a handle to a value which may be passed to C.
+func handleToC(h cgo.Handle) C.uintptr_t {
+	return C.uintptr_t(h)
+}
+
+// cpHandle converts a data pointer received from C back to a handle.
+func cpHandle(p unsafe.Pointer) cgo.Handle {
+	return cgo.Handle(uintptr(p))
+}
+
+// cpHandleValue returns a Go value registered under a data pointer received from C.
+func cpHandleValue(p unsafe.Pointer) interface{} {
+	return cpHandle(p).Value()
+}
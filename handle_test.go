@@ -0,0 +1,81 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_HandleNewDelete(t *testing.T) {
+	live := atomic.LoadInt64(&liveHandles)
+
+	h := handleNew("data")
+	assert.Equal(t, live+1, atomic.LoadInt64(&liveHandles))
+	assert.Equal(t, "data", h.Value())
+
+	handleDelete(h)
+	assert.Equal(t, live, atomic.LoadInt64(&liveHandles))
+}
+
+func Test_HandleCallbacksReleased(t *testing.T) {
+	s := SpaceNew()
+	b := s.AddBody(BodyNew(1.0, 1.0))
+	c := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	live := atomic.LoadInt64(&liveHandles)
+
+	s.EachBody(func(Body) {})
+	s.EachShape(func(Shape) {})
+	s.EachConstraint(func(Constraint) {})
+	s.BBQuery(BBNew(-1, -1, 1, 1), AllLayers, NoGroup, func(Shape) {})
+	s.PointQuery(Origin(), AllLayers, NoGroup, func(Shape) {})
+	s.NearestPointQuery(Origin(), 1.0, AllLayers, NoGroup, func(Shape, float64, Vect) {})
+	s.SegmentQuery(VectNew(-2, 0), VectNew(2, 0), AllLayers, NoGroup, func(Shape, float64, Vect) {})
+	b.EachShape(func(Body, Shape) {})
+	b.EachConstraint(func(Body, Constraint) {})
+	b.EachArbiter(func(Body, Arbiter) {})
+
+	assert.Equal(t, live, atomic.LoadInt64(&liveHandles))
+
+	s.RemoveShape(c)
+	s.RemoveBody(b)
+	c.Free()
+	b.Free()
+	s.Free()
+}
+
+func Test_HandleSpaceFreeReleases(t *testing.T) {
+	live := atomic.LoadInt64(&liveHandles)
+	s := SpaceNew()
+
+	s.AddPostStepCallback(func(Space, interface{}) {}, 1)
+	s.AddCollisionHandler(1, 2, nil, nil, nil, nil, nil)
+	s.SetDefaultCollisionHandler(nil, nil, nil, nil, nil)
+	// collision handlers are looked up by the space, they hold no handles
+	assert.Equal(t, live+1, atomic.LoadInt64(&liveHandles))
+
+	s.Free()
+	assert.Equal(t, live, atomic.LoadInt64(&liveHandles))
+}
@@ -0,0 +1,79 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+////////////////////////////////////////////////////////////////////////////////
+
+// MassProperties are the mass, moment of inertia and center of gravity of a shape
+// or a group of shapes.
+type MassProperties struct {
+	// Mass is the mass.
+	Mass float64
+	// Moment is the moment of inertia about the centroid.
+	Moment float64
+	// Centroid is the center of gravity in body local coordinates.
+	Centroid Vect
+}
+
+// massShape is a shape which mass properties can be computed.
+type massShape interface {
+	MassProperties(density float64) MassProperties
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Add combines two mass properties using the parallel axis theorem.
+func (p MassProperties) Add(q MassProperties) MassProperties {
+	m := p.Mass + q.Mass
+	if m <= 0.0 {
+		return MassProperties{}
+	}
+
+	c := p.Centroid.Mul(p.Mass).Add(q.Centroid.Mul(q.Mass)).Div(m)
+	dp, dq := p.Centroid.Sub(c), q.Centroid.Sub(c)
+
+	return MassProperties{
+		Mass:     m,
+		Moment:   p.Moment + p.Mass*dp.Dot(dp) + q.Moment + q.Mass*dq.Dot(dq),
+		Centroid: c,
+	}
+}
+
+// MomentAbout returns the moment of inertia about a point in body local coordinates.
+func (p MassProperties) MomentAbout(point Vect) float64 {
+	d := p.Centroid.Sub(point)
+	return p.Moment + p.Mass*d.Dot(d)
+}
+
+// moveShape moves the geometry of a shape in body local coordinates.
+func moveShape(sh Shape, offset Vect) {
+	switch sh := sh.(type) {
+	case CircleShape:
+		sh.SetOffset(sh.Offset().Add(offset))
+	case SegmentShape:
+		sh.SetEndpoints(sh.A().Add(offset), sh.B().Add(offset))
+	case PolyShape:
+		sh.SetVerts(sh.VertsLocal(), offset)
+	}
+}
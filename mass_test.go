@@ -0,0 +1,74 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"math"
+	"testing"
+)
+
+// nearlyEqual reports whether two floats are equal within a small tolerance.
+func nearlyEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func Test_MassPropertiesAdd(t *testing.T) {
+	p := MassProperties{1.0, 0.5, VectNew(-1.0, 0.0)}
+	q := MassProperties{3.0, 1.5, VectNew(1.0, 0.0)}
+
+	sum := p.Add(q)
+	assert.Equal(t, 4.0, sum.Mass)
+	assert.Equal(t, VectNew(0.5, 0.0), sum.Centroid)
+	assert.T(t, nearlyEqual(0.5+1.0*1.5*1.5+1.5+3.0*0.5*0.5, sum.Moment))
+	assert.T(t, nearlyEqual(sum.Moment+4.0*0.25, sum.MomentAbout(Origin())))
+
+	assert.Equal(t, p, MassProperties{}.Add(p))
+}
+
+func Test_ShapeMassProperties(t *testing.T) {
+	b := BodyNew(1.0, 1.0)
+	defer b.Free()
+
+	circle := CircleShapeNew(b, 1.0, VectNew(1.0, 2.0))
+	defer circle.Free()
+	p := circle.MassProperties(2.0)
+	assert.T(t, nearlyEqual(2.0*math.Pi, p.Mass))
+	assert.T(t, nearlyEqual(math.Pi, p.Moment))
+	assert.Equal(t, VectNew(1.0, 2.0), p.Centroid)
+
+	segment := SegmentShapeNew(b, VectNew(0.0, 0.0), VectNew(2.0, 0.0), 0.5)
+	defer segment.Free()
+	p = segment.MassProperties(1.0)
+	assert.T(t, nearlyEqual(AreaForSegment(segment.A(), segment.B(), 0.5), p.Mass))
+	assert.T(t, nearlyEqual(p.Mass*4.0/12.0, p.Moment))
+	assert.Equal(t, VectNew(1.0, 0.0), p.Centroid)
+
+	box := BoxShapeNew2(b, BBNew(0.0, 0.0, 2.0, 4.0)).(PolyShape)
+	defer box.Free()
+	p = box.MassProperties(0.5)
+	assert.T(t, nearlyEqual(4.0, p.Mass))
+	assert.T(t, nearlyEqual(4.0*(4.0+16.0)/12.0, p.Moment))
+	assert.T(t, nearlyEqual(0.0, p.Centroid.Dist(VectNew(1.0, 2.0))))
+}
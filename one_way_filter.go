@@ -0,0 +1,105 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+////////////////////////////////////////////////////////////////////////////////
+
+// OneWayFilter is a collision filter making the shapes of the first collision type
+// it's added for collidable from one side only, e.g. platforms to jump through
+// from below and land on from above:
+//
+//	f := OneWayFilterNew(VectNew(0.0, 1.0))
+//	space.AddCollisionFilter(platformType, AnyCollisionType, f)
+//
+// Shapes touching the other side are ignored until they separate, so they pass
+// through. Bodies may also be let through for a while from either side.
+type OneWayFilter struct {
+	direction Vect
+	drops     map[Body]oneWayDrop
+	now       float64
+}
+
+// oneWayDrop is a body let through the shapes of a one-way filter.
+type oneWayDrop struct {
+	timeout  float64
+	deadline float64
+	started  bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Direction returns the direction the shapes are collidable from.
+func (f *OneWayFilter) Direction() Vect {
+	return f.direction
+}
+
+// DropThrough lets a body pass through the shapes for a timeout in simulated
+// time, as measured by the space of the body from the first time the body touches
+// the shapes. A body resting on the shapes falls through them.
+// The body doesn't need to be in a space yet.
+func (f *OneWayFilter) DropThrough(b Body, timeout float64) {
+	for body, d := range f.drops {
+		if d.started && f.now >= d.deadline {
+			delete(f.drops, body)
+		}
+	}
+
+	f.drops[b] = oneWayDrop{timeout: timeout}
+}
+
+// OneWayFilterNew creates a filter making shapes collidable from a direction only,
+// pointing from the shapes towards the side where others collide with them.
+func OneWayFilterNew(direction Vect) *OneWayFilter {
+	return &OneWayFilter{direction: direction, drops: make(map[Body]oneWayDrop)}
+}
+
+// PreSolve implements CollisionFilter.
+func (f *OneWayFilter) PreSolve(s Space, arb Arbiter) bool {
+	_, b := arb.Bodies()
+	f.now = s.Time()
+
+	if d, ok := f.drops[b]; ok {
+		if !d.started {
+			d.deadline, d.started = f.now+d.timeout, true
+			f.drops[b] = d
+		}
+		if f.now < d.deadline {
+			arb.Ignore()
+			return false
+		}
+		delete(f.drops, b)
+	}
+
+	// the normal points from the one-way shape to the other one
+	if arb.Normal(0).Dot(f.direction) < 0.0 {
+		arb.Ignore()
+		return false
+	}
+	return true
+}
+
+// SetDirection sets the direction the shapes are collidable from.
+func (f *OneWayFilter) SetDirection(direction Vect) {
+	f.direction = direction
+}
@@ -0,0 +1,153 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_OneWayFilterFromAbove(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	platform := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	platform.SetCollisionType(1)
+
+	f := OneWayFilterNew(VectNew(0.0, 1.0))
+	s.AddCollisionFilter(1, AnyCollisionType, f)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 3.0))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	handler := 0
+	s.SetCollisionHandler(1, 2, CollisionHandlerFuncs{
+		PreSolveFunc: func(s Space, arb Arbiter) bool {
+			handler++
+			return true
+		},
+	})
+
+	for i := 0; i < 60; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, b.Position().Y > 0.9, "ball fell through: %v", b.Position())
+	assert.T(t, handler > 0)
+
+	s.Free()
+}
+
+func Test_OneWayFilterFromBelow(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	platform := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	platform.SetCollisionType(1)
+
+	f := OneWayFilterNew(VectNew(0.0, 1.0))
+	s.AddCollisionFilter(1, AnyCollisionType, f)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, -2.0))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	b.SetVelocity(VectNew(0.0, 40.0))
+	for i := 0; i < 120; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, b.Position().Y > 0.9, "ball didn't land on the platform: %v", b.Position())
+
+	s.Free()
+}
+
+func Test_OneWayFilterDropThrough(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	platform := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	platform.SetCollisionType(1)
+
+	f := OneWayFilterNew(VectNew(0.0, 1.0))
+	s.AddCollisionFilter(1, AnyCollisionType, f)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 1.0))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	for i := 0; i < 30; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.T(t, b.Position().Y > 0.9)
+
+	f.DropThrough(b, 0.1)
+	for i := 0; i < 30; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, b.Position().Y < -1.0, "ball didn't drop through: %v", b.Position())
+
+	// the drop times out, so the ball lands again when thrown up
+	b.SetVelocity(VectNew(0.0, 40.0))
+	for i := 0; i < 120; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, b.Position().Y > 0.9, "ball didn't land on the platform: %v", b.Position())
+
+	s.Free()
+}
+
+func Test_OneWayFilterDropThroughLocked(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	platform := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	platform.SetCollisionType(1)
+
+	f := OneWayFilterNew(VectNew(0.0, 1.0))
+	s.AddCollisionFilter(1, AnyCollisionType, f)
+
+	// locks the space while iterating the bodies
+	s.AddBody(BodyNew(1.0, 1.0))
+
+	var b Body
+	whileLocked(s, func() {
+		b = s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+		b.SetPosition(VectNew(0.0, 1.0))
+		ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+		ball.SetCollisionType(2)
+
+		f.DropThrough(b, 0.1)
+	})
+	assert.T(t, s.Contains(b))
+
+	for i := 0; i < 30; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, b.Position().Y < -1.0, "ball didn't drop through: %v", b.Position())
+
+	s.Free()
+}
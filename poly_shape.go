@@ -60,6 +60,15 @@ func BoxShapeNew3(b Body, box BB, radius float64) Shape {
 	return PolyShape{cpshape(s)}
 }
 
+// MassProperties returns the mass properties of the polygon for a density.
+// The radius of the polygon is ignored.
+func (s PolyShape) MassProperties(density float64) MassProperties {
+	verts := s.VertsLocal()
+	c := CentroidForPoly(verts)
+	m := density * AreaForPoly(verts)
+	return MassProperties{m, MomentForPoly(m, verts, c.Neg()), c}
+}
+
 // NumVerts returns the number of vertices in a polygon shape.
 func (s PolyShape) NumVerts() int {
 	return int(C.cpPolyShapeGetNumVerts(s.c()))
@@ -111,6 +120,15 @@ func (s PolyShape) VertLocal(idx int) Vect {
 	return cpVect(C.cpPolyShapeGetVert(s.c(), C.int(idx)))
 }
 
+// VertsLocal returns a copy of the vertex positions (local coordinates).
+func (s PolyShape) VertsLocal() []Vect {
+	verts := make([]Vect, s.NumVerts())
+	for i := range verts {
+		verts[i] = s.VertLocal(i)
+	}
+	return verts
+}
+
 // VertsWorld returns vertex positions (world coordinates).
 func (s PolyShape) VertsWorld() []Vect {
 	num := s.NumVerts()
@@ -0,0 +1,282 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"fmt"
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Bone describes a bone of a skeleton: a body with a capsule shaped segment shape,
+// attached to its parent bone by a pivot joint with angular limits.
+type Bone struct {
+	// Name identifies the bone in the skeleton.
+	Name string
+	// Parent is the name of the bone this one is attached to, empty for the root.
+	Parent string
+	// Attach is where the bone starts along its parent, from 0 (the start of the
+	// parent) to 1 (its end).
+	Attach float64
+	// Length, Radius and Mass of the bone.
+	Length float64
+	Radius float64
+	Mass   float64
+	// Angle is the rest angle of the bone relative to its parent, or the angle of
+	// the root bone.
+	Angle float64
+	// MinAngle and MaxAngle limit the angle of the bone relative to its parent.
+	MinAngle float64
+	MaxAngle float64
+	// Stiffness and Damping of a damped rotary spring pulling the bone to its rest
+	// angle (muscle tone). If Stiffness is zero, there is no spring.
+	Stiffness float64
+	Damping   float64
+}
+
+// Ragdoll is a set of bodies connected by joints as described by a skeleton.
+type Ragdoll struct {
+	space       Space
+	skeleton    Skeleton
+	index       map[string]int
+	bodies      []Body
+	shapes      []SegmentShape
+	constraints []Constraint
+}
+
+// Skeleton describes a creature as bones, every parent listed before its children.
+type Skeleton struct {
+	Bones []Bone
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Body returns the body of the bone with a name, or 0 if there is no such bone.
+func (r *Ragdoll) Body(name string) Body {
+	if i, ok := r.index[name]; ok {
+		return r.bodies[i]
+	}
+	return 0
+}
+
+// Bodies returns the bodies of the bones, in the order of the skeleton.
+func (r *Ragdoll) Bodies() []Body {
+	return r.bodies
+}
+
+// Constraints returns the joints, angular limits and springs between the bones.
+func (r *Ragdoll) Constraints() []Constraint {
+	return r.constraints
+}
+
+// Free removes the bones and their joints from the space and frees them.
+func (r *Ragdoll) Free() {
+	for _, c := range r.constraints {
+		r.space.RemoveConstraint(c)
+		c.Free()
+	}
+	for i, b := range r.bodies {
+		r.space.RemoveShape(r.shapes[i])
+		r.shapes[i].Free()
+		r.space.RemoveBody(b)
+		b.Free()
+	}
+
+	r.bodies, r.shapes, r.constraints = nil, nil, nil
+}
+
+// HumanoidSkeleton returns the skeleton of a humanoid of a height and a mass,
+// standing upright with its arms hanging. The root bone is the torso, starting at
+// the hips. Its bones are "torso", "head", "upperArmLeft", "lowerArmLeft",
+// "upperArmRight", "lowerArmRight", "thighLeft", "shinLeft", "thighRight" and "shinRight".
+func HumanoidSkeleton(height, mass float64) Skeleton {
+	h, m := height, mass
+	limb := func(name, parent string, attach, length, radius, mass, angle, min, max float64) Bone {
+		return Bone{
+			Name:     name,
+			Parent:   parent,
+			Attach:   attach,
+			Length:   length * h,
+			Radius:   radius * h,
+			Mass:     mass * m,
+			Angle:    angle,
+			MinAngle: angle + min,
+			MaxAngle: angle + max,
+		}
+	}
+
+	bones := []Bone{
+		{Name: "torso", Length: 0.3 * h, Radius: 0.07 * h, Mass: 0.49 * m, Angle: math.Pi / 2.0},
+		limb("head", "torso", 1.0, 0.12, 0.06, 0.08, 0.0, -math.Pi/4.0, math.Pi/4.0),
+	}
+	for _, side := range []string{"Left", "Right"} {
+		bones = append(bones,
+			limb("upperArm"+side, "torso", 0.95, 0.17, 0.025, 0.03, math.Pi, -3.0*math.Pi/4.0, 3.0*math.Pi/4.0),
+			limb("lowerArm"+side, "upperArm"+side, 1.0, 0.16, 0.025, 0.025, 0.0, 0.0, 2.5),
+			limb("thigh"+side, "torso", 0.0, 0.24, 0.035, 0.1, math.Pi, -math.Pi/2.0, math.Pi/4.0),
+			limb("shin"+side, "thigh"+side, 1.0, 0.25, 0.035, 0.06, 0.0, -2.5, 0.0))
+	}
+
+	return Skeleton{bones}
+}
+
+// Pose moves the bones to angles relative to their parents, or to the absolute
+// angle for the root bone, keeping the start of the root bone in place. Bones
+// missing from angles get their rest angle. The bones are stopped.
+func (r *Ragdoll) Pose(angles map[string]float64) {
+	root := r.bodies[0]
+	start := root.LocalToWorld(boneStart(r.skeleton.Bones[0]))
+
+	for i, p := range r.skeleton.layout(start, angles) {
+		b := r.bodies[i]
+		b.SetPosition(p.center)
+		b.SetAngle(p.angle)
+		b.SetVelocity(Origin())
+		b.SetAngularVelocity(0.0)
+		b.Activate()
+	}
+}
+
+// RagdollNew creates the bodies of the bones of a skeleton, connects them with
+// joints and adds them to a space. The root bone starts at a position. All the
+// shapes are in a collision group, so that the bones don't collide with each
+// other, it should be distinct for every ragdoll.
+func RagdollNew(s Space, sk Skeleton, pos Vect, group Group) (*Ragdoll, error) {
+	index, err := sk.validate()
+	if err != nil {
+		return nil, checkError("RagdollNew", err)
+	}
+
+	r := &Ragdoll{space: s, skeleton: sk, index: index}
+
+	for i, p := range sk.layout(pos, nil) {
+		bone := sk.Bones[i]
+		a, b := boneStart(bone), boneStart(bone).Neg()
+
+		body := s.AddBody(BodyNew(bone.Mass, MomentForSegment(bone.Mass, a, b)))
+		body.SetPosition(p.center)
+		body.SetAngle(p.angle)
+
+		sh := SegmentShapeNew(body, a, b, bone.Radius)
+		sh.SetGroup(group)
+		s.AddShape(sh)
+
+		r.bodies = append(r.bodies, body)
+		r.shapes = append(r.shapes, sh)
+
+		if "" == bone.Parent {
+			continue
+		}
+
+		parent := r.bodies[index[bone.Parent]]
+		r.addConstraint(PivotJointNew(parent, body, body.LocalToWorld(a)))
+		r.addConstraint(RotaryLimitJointNew(parent, body, bone.MinAngle, bone.MaxAngle))
+		if 0.0 != bone.Stiffness {
+			r.addConstraint(DampedRotarySpringNew(parent, body, bone.Angle, bone.Stiffness, bone.Damping))
+		}
+	}
+
+	return r, nil
+}
+
+// addConstraint adds a constraint between bones to the space.
+func (r *Ragdoll) addConstraint(c Constraint) {
+	r.space.AddConstraint(c)
+	r.constraints = append(r.constraints, c)
+}
+
+// boneStart returns the start of a bone in its body local coordinates.
+func boneStart(b Bone) Vect {
+	return VectNew(-b.Length/2.0, 0.0)
+}
+
+// bonePlacement is a position and an angle of a bone.
+type bonePlacement struct {
+	center Vect
+	angle  float64
+}
+
+// layout places the bones with the root bone starting at a position. Relative
+// angles are taken from angles, or the rest angles of the bones.
+func (sk Skeleton) layout(pos Vect, angles map[string]float64) []bonePlacement {
+	placements := make([]bonePlacement, len(sk.Bones))
+	starts := make(map[string]Vect)
+	abs := make(map[string]float64)
+
+	for i, bone := range sk.Bones {
+		angle, ok := angles[bone.Name]
+		if !ok {
+			angle = bone.Angle
+		}
+
+		start := pos
+		if "" != bone.Parent {
+			parent := sk.Bones[0]
+			for _, b := range sk.Bones {
+				if b.Name == bone.Parent {
+					parent = b
+				}
+			}
+
+			angle += abs[parent.Name]
+			start = starts[parent.Name].Add(VectForAngle(abs[parent.Name]).Mul(bone.Attach * parent.Length))
+		}
+
+		starts[bone.Name], abs[bone.Name] = start, angle
+		placements[i] = bonePlacement{start.Add(VectForAngle(angle).Mul(bone.Length / 2.0)), angle}
+	}
+
+	return placements
+}
+
+// validate checks the skeleton and returns the indices of its bones by name.
+func (sk Skeleton) validate() (map[string]int, error) {
+	if 0 == len(sk.Bones) {
+		return nil, fmt.Errorf("%w: skeleton has no bones", ErrInvalidArgument)
+	}
+
+	index := make(map[string]int)
+	for i, b := range sk.Bones {
+		if _, ok := index[b.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate bone %q", ErrInvalidArgument, b.Name)
+		}
+
+		_, ok := index[b.Parent]
+		switch {
+		case 0 == i && "" != b.Parent:
+			return nil, fmt.Errorf("%w: root bone %q has a parent", ErrInvalidArgument, b.Name)
+		case i > 0 && !ok:
+			return nil, fmt.Errorf("%w: parent of bone %q is not listed before it", ErrInvalidArgument, b.Name)
+		case !(b.Length > 0.0) || !(b.Mass > 0.0) || b.Radius < 0.0:
+			return nil, fmt.Errorf("%w: bone %q needs a positive length and mass", ErrInvalidArgument, b.Name)
+		case b.MinAngle > b.MaxAngle:
+			return nil, fmt.Errorf("%w: bone %q has min angle greater than max", ErrInvalidArgument, b.Name)
+		}
+
+		index[b.Name] = i
+	}
+
+	return index, nil
+}
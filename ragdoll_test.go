@@ -0,0 +1,120 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"errors"
+	"github.com/bmizerany/assert"
+	"math"
+	"testing"
+)
+
+func Test_RagdollNew(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	sk := HumanoidSkeleton(1.8, 70.0)
+	sk.Bones[1].Stiffness, sk.Bones[1].Damping = 100.0, 10.0
+
+	r, err := RagdollNew(s, sk, VectNew(0.0, 1.0), 1)
+	assert.Equal(t, nil, err)
+	defer r.Free()
+
+	assert.Equal(t, 10, len(r.Bodies()))
+	assert.Equal(t, 9*2+1, len(r.Constraints()))
+	assert.Equal(t, Body(0), r.Body("tail"))
+
+	torso, head := r.Body("torso"), r.Body("head")
+	assert.T(t, head.Position().Y > torso.Position().Y)
+	assert.T(t, r.Body("shinLeft").Position().Y < r.Body("thighLeft").Position().Y)
+
+	// the bones overlap at the joints, but must not push each other apart
+	for i := 0; i < 60; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	for _, b := range r.Bodies() {
+		assert.Tf(t, b.Velocity().Length() < 1e-6, "bone is moving: %v", b.Velocity())
+	}
+}
+
+func Test_RagdollNewInvalid(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	sk := HumanoidSkeleton(1.8, 70.0)
+	sk.Bones[0], sk.Bones[1] = sk.Bones[1], sk.Bones[0]
+
+	_, err := RagdollNew(s, sk, Origin(), 1)
+	assert.T(t, errors.Is(err, ErrInvalidArgument))
+
+	_, err = RagdollNew(s, Skeleton{}, Origin(), 1)
+	assert.T(t, errors.Is(err, ErrInvalidArgument))
+
+	for range s.Bodies() {
+		t.Fatal("bodies were added for an invalid skeleton")
+	}
+}
+
+func Test_RagdollLocked(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+	s.AddBody(BodyNew(1.0, 1.0))
+
+	var r *Ragdoll
+	whileLocked(s, func() {
+		r, _ = RagdollNew(s, HumanoidSkeleton(1.8, 70.0), VectNew(0.0, 1.0), 1)
+		assert.T(t, !s.Contains(r.Body("head")))
+	})
+	for _, b := range r.Bodies() {
+		assert.T(t, s.Contains(b))
+	}
+
+	whileLocked(s, r.Free)
+	n := 0
+	for range s.Bodies() {
+		n++
+	}
+	assert.Equal(t, 1, n)
+}
+
+func Test_RagdollPose(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	r, _ := RagdollNew(s, HumanoidSkeleton(1.8, 70.0), VectNew(0.0, 1.0), 1)
+	defer r.Free()
+
+	hips := r.Body("torso").LocalToWorld(VectNew(-0.3*1.8/2.0, 0.0))
+
+	// raise the left arm over the head
+	r.Pose(map[string]float64{"upperArmLeft": 0.0})
+	assert.Tf(t, math.Abs(r.Body("upperArmLeft").Angle()-math.Pi/2.0) < 1e-9, "%v", r.Body("upperArmLeft").Angle())
+	assert.T(t, r.Body("lowerArmLeft").Position().Y > r.Body("head").Position().Y)
+	assert.T(t, r.Body("lowerArmRight").Position().Y < r.Body("torso").Position().Y)
+
+	// lie down, the hips stay in place
+	r.Pose(map[string]float64{"torso": 0.0})
+	assert.Tf(t, r.Body("torso").LocalToWorld(VectNew(-0.3*1.8/2.0, 0.0)).Sub(hips).Length() < 1e-9, "hips moved")
+	assert.Tf(t, math.Abs(r.Body("head").Position().Y-1.0) < 1e-9, "%v", r.Body("head").Position())
+}
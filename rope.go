@@ -0,0 +1,277 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Rope is a chain of link bodies between two bodies. Each link is a body with a
+// segment shape, connected to the next one at its end. The first link hangs from
+// the first body by a winch, a slide joint which length changes when the rope is
+// wound or unwound.
+type Rope struct {
+	space      Space
+	a, b       Body
+	anchorA    Vect
+	anchorB    Vect
+	opts       RopeOptions
+	linkLength float64
+	winch      float64
+	links      []Body
+	shapes     []SegmentShape
+	joints     []Constraint // joints[i] connects links[i-1] (or a) to links[i] (or b)
+	springs    []DampedRotarySpring
+}
+
+// RopeOptions configure the links of a rope.
+type RopeOptions struct {
+	// LinkMass is the mass of every link, zero means 1.
+	LinkMass float64
+	// Radius is the radius of the segment shapes of the links.
+	Radius float64
+	// Group is the collision group of the segment shapes of the links.
+	// Set it to keep adjacent links from colliding.
+	Group Group
+	// Slack is how far apart the ends of adjacent links may get. If it's zero,
+	// links are connected by pivot joints, otherwise by slide joints.
+	Slack float64
+	// Stiffness and Damping of the damped rotary springs keeping adjacent links
+	// straight. If Stiffness is zero, there are no springs.
+	Stiffness float64
+	Damping   float64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// BridgeNew creates a plank bridge between two bodies: a rope which adjacent planks
+// are kept straight by damped rotary springs of a stiffness and a damping.
+func BridgeNew(s Space, a Body, anchorA Vect, b Body, anchorB Vect, planks int,
+	stiffness, damping float64, opts RopeOptions) *Rope {
+
+	opts.Stiffness, opts.Damping = stiffness, damping
+	return RopeNew(s, a, anchorA, b, anchorB, planks, opts)
+}
+
+// RopeNew creates a rope of a number of links stretched between two bodies at
+// anchors (local coordinates) and adds it to a space.
+func RopeNew(s Space, a Body, anchorA Vect, b Body, anchorB Vect, segments int, opts RopeOptions) *Rope {
+	if segments < 1 {
+		panic("invalid number of segments in RopeNew()")
+	}
+
+	pa, pb := a.LocalToWorld(anchorA), b.LocalToWorld(anchorB)
+	dist := pa.Dist(pb)
+	if 0.0 == dist {
+		panic("rope anchors at the same point in RopeNew()")
+	}
+
+	if 0.0 == opts.LinkMass {
+		opts.LinkMass = 1.0
+	}
+
+	r := &Rope{
+		space:      s,
+		a:          a,
+		b:          b,
+		anchorA:    anchorA,
+		anchorB:    anchorB,
+		opts:       opts,
+		linkLength: dist / float64(segments),
+	}
+
+	dir := pb.Sub(pa).Div(dist)
+	for i := 0; i < segments; i++ {
+		center := pa.Add(dir.Mul(r.linkLength * (float64(i) + 0.5)))
+		r.links = append(r.links, r.newLink(center, dir.ToAngle()))
+	}
+
+	r.joints = append(r.joints, r.newWinch(r.links[0]))
+	for i := 1; i < segments; i++ {
+		r.joints = append(r.joints, r.newJoint(r.links[i-1], r.linkEnd(), r.links[i], r.linkStart()))
+		r.springs = append(r.springs, r.newSpring(r.links[i-1], r.links[i])...)
+	}
+	r.joints = append(r.joints, r.newJoint(r.links[segments-1], r.linkEnd(), b, anchorB))
+
+	return r
+}
+
+// Free removes the links and the joints of the rope from the space and frees them.
+func (r *Rope) Free() {
+	for _, sp := range r.springs {
+		r.freeConstraint(sp)
+	}
+	for _, j := range r.joints {
+		r.freeConstraint(j)
+	}
+	for i := range r.links {
+		r.freeLink(i)
+	}
+
+	r.links, r.shapes, r.joints, r.springs = nil, nil, nil, nil
+}
+
+// Length returns the current rest length of the rope: the length of its links and
+// of its winch.
+func (r *Rope) Length() float64 {
+	return float64(len(r.links))*r.linkLength + r.winch
+}
+
+// LinkLength returns the length of every link.
+func (r *Rope) LinkLength() float64 {
+	return r.linkLength
+}
+
+// Links returns the link bodies, from the first body to the second one.
+func (r *Rope) Links() []Body {
+	return r.links
+}
+
+// Wind shortens the rope by pulling a length in at the first body, removing links
+// as they reach it. A negative length unwinds the rope, adding links.
+// There is always at least one link left.
+func (r *Rope) Wind(length float64) {
+	r.winch -= length
+
+	for r.winch < 0.0 && len(r.links) > 1 {
+		r.removeFirstLink()
+		r.winch += r.linkLength
+	}
+	for r.winch >= r.linkLength {
+		r.winch -= r.linkLength
+		r.addFirstLink()
+	}
+
+	r.winch = math.Max(r.winch, 0.0)
+	r.joints[0].(SlideJoint).SetMax(r.winch)
+	r.joints[0].ActivateBodies()
+}
+
+// addFirstLink adds a link between the first body and the first link.
+func (r *Rope) addFirstLink() {
+	first := r.links[0]
+	start := first.LocalToWorld(r.linkStart())
+
+	dir := start.Sub(r.a.LocalToWorld(r.anchorA))
+	if l := dir.Length(); l > 0.0 {
+		dir = dir.Div(l)
+	} else {
+		dir = first.Rotation()
+	}
+
+	link := r.newLink(start.Sub(dir.Mul(r.linkLength/2.0)), dir.ToAngle())
+	link.SetVelocity(first.Velocity())
+
+	r.freeConstraint(r.joints[0])
+	joint := r.newJoint(link, r.linkEnd(), first, r.linkStart())
+
+	r.links = append([]Body{link}, r.links...)
+	r.shapes = append([]SegmentShape{r.shapes[len(r.shapes)-1]}, r.shapes[:len(r.shapes)-1]...)
+	r.joints = append([]Constraint{r.newWinch(link), joint}, r.joints[1:]...)
+	r.springs = append(r.newSpring(link, first), r.springs...)
+}
+
+// freeConstraint removes a constraint from the space and frees it.
+func (r *Rope) freeConstraint(c Constraint) {
+	r.space.RemoveConstraint(c)
+	c.Free()
+}
+
+// freeLink removes a link and its shape from the space and frees them.
+func (r *Rope) freeLink(i int) {
+	r.space.RemoveShape(r.shapes[i])
+	r.shapes[i].Free()
+	r.space.RemoveBody(r.links[i])
+	r.links[i].Free()
+}
+
+// linkEnd returns the end of a link in its local coordinates.
+func (r *Rope) linkEnd() Vect {
+	return VectNew(r.linkLength/2.0, 0.0)
+}
+
+// linkStart returns the start of a link in its local coordinates.
+func (r *Rope) linkStart() Vect {
+	return VectNew(-r.linkLength/2.0, 0.0)
+}
+
+// newJoint connects two bodies at anchors with a pivot joint, or a slide joint if
+// the rope has slack, and adds it to the space.
+func (r *Rope) newJoint(a Body, anchorA Vect, b Body, anchorB Vect) Constraint {
+	if r.opts.Slack > 0.0 {
+		return r.space.AddConstraint(SlideJointNew(a, b, anchorA, anchorB, 0.0, r.opts.Slack))
+	}
+	return r.space.AddConstraint(PivotJointNew2(a, b, anchorA, anchorB))
+}
+
+// newLink creates a link body with its shape centered at a point and rotated by
+// an angle and adds them to the space. The shape is appended to the shapes.
+func (r *Rope) newLink(center Vect, angle float64) Body {
+	start, end := r.linkStart(), r.linkEnd()
+
+	link := r.space.AddBody(BodyNew(r.opts.LinkMass, MomentForSegment(r.opts.LinkMass, start, end)))
+	link.SetPosition(center)
+	link.SetAngle(angle)
+
+	sh := SegmentShapeNew(link, start, end, r.opts.Radius)
+	sh.SetGroup(r.opts.Group)
+	r.space.AddShape(sh)
+	r.shapes = append(r.shapes, sh)
+
+	return link
+}
+
+// newSpring creates a damped rotary spring keeping two adjacent links straight
+// and adds it to the space. Returns nothing if the rope has no springs.
+func (r *Rope) newSpring(a, b Body) []DampedRotarySpring {
+	if 0.0 == r.opts.Stiffness {
+		return nil
+	}
+
+	sp := DampedRotarySpringNew(a, b, 0.0, r.opts.Stiffness, r.opts.Damping)
+	r.space.AddConstraint(sp)
+	return []DampedRotarySpring{sp}
+}
+
+// newWinch creates the slide joint of the winch between the first body and the
+// first link and adds it to the space.
+func (r *Rope) newWinch(link Body) Constraint {
+	return r.space.AddConstraint(SlideJointNew(r.a, link, r.anchorA, r.linkStart(), 0.0, r.winch))
+}
+
+// removeFirstLink removes the first link, connecting the next one to the winch.
+func (r *Rope) removeFirstLink() {
+	if len(r.springs) > 0 {
+		r.freeConstraint(r.springs[0])
+		r.springs = r.springs[1:]
+	}
+	r.freeConstraint(r.joints[0])
+	r.freeConstraint(r.joints[1])
+	r.freeLink(0)
+
+	r.links, r.shapes = r.links[1:], r.shapes[1:]
+	r.joints = append([]Constraint{r.newWinch(r.links[0])}, r.joints[2:]...)
+}
@@ -0,0 +1,124 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_RopeWind(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	weight := s.AddBody(BodyNew(1.0, 1.0))
+	rope := RopeNew(s, s.StaticBody(), VectNew(0.0, 10.0), weight, Origin(), 10, RopeOptions{Group: 1})
+	defer rope.Free()
+
+	assert.Equal(t, 1.0, rope.LinkLength())
+	assert.Equal(t, 10.0, rope.Length())
+	assert.Equal(t, 10, len(rope.Links()))
+
+	rope.Wind(3.0)
+	assert.Equal(t, 7, len(rope.Links()))
+	assert.Equal(t, 7.0, rope.Length())
+
+	rope.Wind(-1.5)
+	assert.Equal(t, 8, len(rope.Links()))
+	assert.Equal(t, 8.5, rope.Length())
+
+	for i := 0; i < 120; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, weight.Position().Y > 1.0, "weight wasn't pulled up: %v", weight.Position())
+
+	rope.Wind(100.0)
+	assert.Equal(t, 1, len(rope.Links()))
+	assert.Equal(t, 0.0, rope.Length()-rope.LinkLength())
+}
+
+func Test_RopeLocked(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	weight := s.AddBody(BodyNew(1.0, 1.0))
+	var rope *Rope
+	whileLocked(s, func() {
+		rope = RopeNew(s, s.StaticBody(), VectNew(0.0, 10.0), weight, Origin(), 10, RopeOptions{})
+		rope.Wind(3.0)
+		assert.T(t, !s.Contains(rope.Links()[0]))
+	})
+
+	assert.Equal(t, 7, len(rope.Links()))
+	for _, l := range rope.Links() {
+		assert.T(t, s.Contains(l))
+	}
+
+	whileLocked(s, rope.Free)
+	n := 0
+	for range s.Bodies() {
+		n++
+	}
+	assert.Equal(t, 1, n)
+}
+
+func Test_Bridge(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	bridge := BridgeNew(s, s.StaticBody(), VectNew(-5.0, 0.0), s.StaticBody(), VectNew(5.0, 0.0),
+		10, 1e5, 1e3, RopeOptions{Group: 1})
+	defer bridge.Free()
+
+	// joints and springs between adjacent planks
+	constraints := func() (joints, springs int) {
+		for c := range s.Constraints() {
+			if _, ok := c.(DampedRotarySpring); ok {
+				springs++
+			} else {
+				joints++
+			}
+		}
+		return joints, springs
+	}
+
+	joints, springs := constraints()
+	assert.Equal(t, 11, joints)
+	assert.Equal(t, 9, springs)
+
+	bridge.Wind(2.0)
+	joints, springs = constraints()
+	assert.Equal(t, 9, joints)
+	assert.Equal(t, 7, springs)
+
+	bridge.Wind(-2.0)
+	joints, springs = constraints()
+	assert.Equal(t, 11, joints)
+	assert.Equal(t, 9, springs)
+
+	for i := 0; i < 60; i++ {
+		s.Step(1.0 / 60.0)
+	}
+}
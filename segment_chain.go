@@ -0,0 +1,163 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SegmentChain is a chain of segment shapes along a polyline, with the neighbors
+// of every segment set. It's added to and removed from a space as a whole.
+type SegmentChain struct {
+	Shapes []SegmentShape
+	closed bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SegmentChainNew creates a chain of segment shapes of a radius attached to a body
+// along vertices. If closed is true and there are more than two vertices, the last
+// one is connected to the first one.
+func SegmentChainNew(body Body, verts []Vect, radius float64, closed bool) SegmentChain {
+	c := SegmentChain{closed: closed && len(verts) > 2}
+
+	n := chainSegments(len(verts), c.closed)
+	for i := 0; i < n; i++ {
+		a, b, prev, next := c.segment(verts, i)
+		sh := SegmentShapeNew(body, a, b, radius)
+		sh.SetNeighbors(prev, next)
+		c.Shapes = append(c.Shapes, sh)
+	}
+
+	return c
+}
+
+// Closed returns true if the last vertex of the chain is connected to the first one.
+func (c SegmentChain) Closed() bool {
+	return c.closed
+}
+
+// Free frees all the segments.
+func (c SegmentChain) Free() {
+	for _, sh := range c.Shapes {
+		sh.Free()
+	}
+}
+
+// SetVerts moves the vertices of the chain in place, there must be as many of them
+// as the chain was created with. The segments in a space are reindexed, after the
+// step if the space is locked.
+func (c SegmentChain) SetVerts(verts []Vect) {
+	if len(c.Shapes) != chainSegments(len(verts), c.closed) {
+		panic("invalid number of vertices in SegmentChain.SetVerts()")
+	}
+
+	for i, sh := range c.Shapes {
+		a, b, prev, next := c.segment(verts, i)
+		sh.SetEndpoints(a, b)
+		sh.SetNeighbors(prev, next)
+
+		s := sh.Space()
+		switch {
+		case 0 == s:
+		case s.IsLocked():
+			s.AddPostStepCallback(func(s Space, key interface{}) {
+				s.ReindexShape(key.(SegmentShape))
+			}, sh)
+		default:
+			s.ReindexShape(sh)
+		}
+	}
+}
+
+// Verts returns the vertices of the chain.
+func (c SegmentChain) Verts() []Vect {
+	var verts []Vect
+	for _, sh := range c.Shapes {
+		verts = append(verts, sh.A())
+	}
+	if n := len(c.Shapes); n > 0 && !c.closed {
+		verts = append(verts, c.Shapes[n-1].B())
+	}
+	return verts
+}
+
+// addToSpace adds all the segments to space.
+func (c SegmentChain) addToSpace(s Space) {
+	for _, sh := range c.Shapes {
+		s.AddShape(sh)
+	}
+}
+
+// chainSegments returns the number of segments of a chain of n vertices.
+func chainSegments(n int, closed bool) int {
+	switch {
+	case n < 2:
+		return 0
+	case closed:
+		return n
+	}
+	return n - 1
+}
+
+// containedInSpace returns true if the space contains all the segments.
+func (c SegmentChain) containedInSpace(s Space) bool {
+	for _, sh := range c.Shapes {
+		if !s.Contains(sh) {
+			return false
+		}
+	}
+	return len(c.Shapes) > 0
+}
+
+// removeFromSpace removes all the segments from space.
+func (c SegmentChain) removeFromSpace(s Space) {
+	for _, sh := range c.Shapes {
+		s.RemoveShape(sh)
+	}
+}
+
+// segment returns the endpoints of the segment i of the chain and the vertices
+// before and after them, which are the endpoints themselves at the open ends.
+func (c SegmentChain) segment(verts []Vect, i int) (a, b, prev, next Vect) {
+	n := len(verts)
+	a, b = verts[i], verts[(i+1)%n]
+	prev, next = a, b
+
+	if i > 0 || c.closed {
+		prev = verts[(i+n-1)%n]
+	}
+	if i+2 < n || c.closed {
+		next = verts[(i+2)%n]
+	}
+
+	return a, b, prev, next
+}
+
+// shapes returns the segments.
+func (c SegmentChain) shapes() []Shape {
+	shapes := make([]Shape, len(c.Shapes))
+	for i, sh := range c.Shapes {
+		shapes[i] = sh
+	}
+	return shapes
+}
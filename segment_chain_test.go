@@ -0,0 +1,79 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_SegmentChainNew(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	verts := []Vect{{0, 0}, {1, 0}, {2, 1}, {3, 1}}
+	open := SegmentChainNew(s.StaticBody(), verts, 0.0, false)
+	defer open.Free()
+	assert.Equal(t, 3, len(open.Shapes))
+	assert.Equal(t, verts, open.Verts())
+	assert.T(t, !open.Closed())
+
+	closed := SegmentChainNew(s.StaticBody(), verts, 0.0, true)
+	defer closed.Free()
+	assert.Equal(t, 4, len(closed.Shapes))
+	assert.Equal(t, verts, closed.Verts())
+	assert.Equal(t, verts[0], closed.Shapes[3].B())
+
+	assert.Equal(t, 0, len(SegmentChainNew(s.StaticBody(), verts[:1], 0.0, true).Shapes))
+
+	// two vertices make a single segment, even if closed
+	pair := SegmentChainNew(s.StaticBody(), verts[:2], 0.0, true)
+	defer pair.Free()
+	assert.Equal(t, 1, len(pair.Shapes))
+	assert.T(t, !pair.Closed())
+	assert.Equal(t, verts[:2], pair.Verts())
+}
+
+func Test_SegmentChainSpace(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	chain := SegmentChainNew(s.StaticBody(), []Vect{{-10, 0}, {0, 0}, {10, 0}}, 0.0, false)
+	defer chain.Free()
+
+	s.Add(chain)
+	assert.T(t, s.Contains(chain))
+
+	// moved in place and reindexed
+	moved := []Vect{{-10, 5}, {0, 5}, {10, 5}}
+	chain.SetVerts(moved)
+	assert.Equal(t, moved, chain.Verts())
+	info := s.NearestPointQueryNearest(VectNew(-5, 5), 1.0, AllLayers, NoGroup)
+	assert.Equal(t, Shape(chain.Shapes[0]), info.Shape)
+	info = s.NearestPointQueryNearest(VectNew(-5, 0), 1.0, AllLayers, NoGroup)
+	assert.Equal(t, nil, info.Shape)
+
+	s.Remove(chain)
+	assert.T(t, !s.Contains(chain))
+}
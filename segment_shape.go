@@ -50,6 +50,15 @@ func (s SegmentShape) B() Vect {
 	return cpVect(C.cpSegmentShapeGetB(s.c()))
 }
 
+// MassProperties returns the mass properties of the segment for a density.
+// The area includes the rounded ends, the moment is the one of a thin line.
+func (s SegmentShape) MassProperties(density float64) MassProperties {
+	a, b := s.A(), s.B()
+	c := a.Add(b).Mul(0.5)
+	m := density * AreaForSegment(a, b, s.Radius())
+	return MassProperties{m, MomentForSegment(m, a.Sub(c), b.Sub(c)), c}
+}
+
 // Normal returns the normal of the segment shape.
 func (s SegmentShape) Normal() Vect {
 	return cpVect(C.cpSegmentShapeGetNormal(s.c()))
@@ -72,7 +81,8 @@ func (s SegmentShape) SetEndpoints(a, b Vect) {
 	C.cpSegmentShapeSetEndpoints(s.c(), a.c(), b.c())
 }
 
-// SetNeighbors FIXME TODO OMG WTF
+// SetNeighbors sets the endpoints of the previous and the next segments in a chain,
+// so that shapes sliding along it don't catch on the joints. See SegmentChainNew.
 func (s SegmentShape) SetNeighbors(prev, next Vect) {
 	C.cpSegmentShapeSetNeighbors(s.c(), prev.c(), next.c())
 }
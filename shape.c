@@ -26,3 +26,11 @@ WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 inline cpShapeType shape_type(cpShape *s) {
 	return s->klass_private->type;
 }
+
+inline uintptr_t shape_get_user_data(cpShape *s) {
+	return (uintptr_t)cpShapeGetUserData(s);
+}
+
+inline void shape_set_user_data(cpShape *s, uintptr_t h) {
+	cpShapeSetUserData(s, (cpDataPointer)h);
+}
@@ -28,6 +28,7 @@ WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 import "C"
 
 import (
+	"runtime/cgo"
 	"unsafe"
 )
 
@@ -125,8 +126,12 @@ func (s shapeBase) Elasticity() float64 {
 }
 
 // Free removes a shape.
+// A shape removed from a locked space is freed once the removal is applied.
 func (s shapeBase) Free() {
-	C.cpShapeFree(s.c())
+	freeAfterPendingOps(unsafe.Pointer(s.c()), func() {
+		s.releaseUserData()
+		C.cpShapeFree(s.c())
+	})
 }
 
 // Friction returns shape's coefficient of friction.
@@ -235,7 +240,11 @@ func (s shapeBase) SetSurfaceVelocity(v Vect) {
 // Generally this points to your the game object so you can access it
 // when given a Shape reference in a callback.
 func (s shapeBase) SetUserData(data interface{}) {
-	C.cpShapeSetUserData(s.c(), dataToC(data))
+	s.releaseUserData()
+
+	if data != nil {
+		C.shape_set_user_data(s.c(), handleToC(handleNew(data)))
+	}
 }
 
 // Space returns space the body was added to or nil if the body doesn't belong to any space.
@@ -255,7 +264,12 @@ func (s shapeBase) Update(pos, rot Vect) BB {
 
 // UserData returns user defined data.
 func (s shapeBase) UserData() interface{} {
-	return cpData(C.cpShapeGetUserData(s.c()))
+	h := cgo.Handle(C.shape_get_user_data(s.c()))
+	if h == 0 {
+		return nil
+	}
+
+	return h.Value()
 }
 
 // addToSpace adds a shape to space.
@@ -300,6 +314,17 @@ func cpshape(s *C.cpShape) shapeBase {
 	return shapeBase(unsafe.Pointer(s))
 }
 
+// releaseUserData releases the handle of user defined data, if any.
+func (s shapeBase) releaseUserData() {
+	h := cgo.Handle(C.shape_get_user_data(s.c()))
+	if h == 0 {
+		return
+	}
+
+	C.shape_set_user_data(s.c(), 0)
+	handleDelete(h)
+}
+
 // removeFromSpace removes a shape from space.
 func (s shapeBase) removeFromSpace(space Space) {
 	space.RemoveShape(cpShape(s.c()))
@@ -25,5 +25,7 @@ WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 #define _GOCHIPMUNK_SHAPE_H
 
 inline cpShapeType shape_type(cpShape *s);
+inline uintptr_t shape_get_user_data(cpShape *s);
+inline void shape_set_user_data(cpShape *s, uintptr_t h);
 
 #endif // !_GOCHIPMUNK_SHAPE_H
@@ -0,0 +1,55 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_ShapeUserData(t *testing.T) {
+	live := atomic.LoadInt64(&liveHandles)
+	b := BodyNew(1.0, 1.0)
+	s := CircleShapeNew(b, 1.0, Origin())
+
+	assert.Equal(t, nil, s.UserData())
+
+	s.SetUserData("first")
+	assert.Equal(t, "first", s.UserData())
+
+	s.SetUserData("second")
+	assert.Equal(t, "second", s.UserData())
+	assert.Equal(t, live+1, atomic.LoadInt64(&liveHandles))
+
+	s.SetUserData(nil)
+	assert.Equal(t, nil, s.UserData())
+	assert.Equal(t, live, atomic.LoadInt64(&liveHandles))
+
+	s.SetUserData("third")
+	s.Free()
+	assert.Equal(t, live, atomic.LoadInt64(&liveHandles))
+
+	b.Free()
+}
@@ -0,0 +1,490 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// #include <chipmunk/chipmunk.h>
+// #include <chipmunk/chipmunk_unsafe.h>
+import "C"
+
+import (
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Snapshot is a saved state of a space and every body, shape and constraint in it.
+// Snapshots are created by Space.Snapshot() and applied by Space.Restore().
+// Objects referenced by a snapshot must not be freed while the snapshot is in use.
+type Snapshot struct {
+	space       Space
+	params      spaceParams
+	bodies      []bodyState
+	shapes      []shapeState
+	constraints []constraintState
+}
+
+// spaceParams is a saved state of the space simulation parameters.
+type spaceParams struct {
+	collisionBias        float64
+	collisionPersistence Timestamp
+	collisionSlop        float64
+	damping              float64
+	enableContactGraph   bool
+	gravity              Vect
+	idleSpeedThreshold   float64
+	iterations           int
+	sleepTimeThreshold   float64
+}
+
+// bodyState is a saved state of a body.
+type bodyState struct {
+	body                 Body
+	inSpace              bool
+	sleeping             bool
+	sleepGroup           Body // root of the sleeping group of the body
+	idleTime             float64
+	mass                 float64
+	moment               float64
+	position             Vect
+	velocity             Vect
+	force                Vect
+	angle                float64
+	angularVelocity      float64
+	torque               float64
+	velocityLimit        float64
+	angularVelocityLimit float64
+}
+
+// shapeState is a saved state of a shape.
+type shapeState struct {
+	shape           Shape
+	body            Body
+	collisionType   CollisionType
+	elasticity      float64
+	friction        float64
+	group           Group
+	layers          Layers
+	sensor          bool
+	surfaceVelocity Vect
+	restoreGeometry func()
+}
+
+// constraintState is a saved state of a constraint.
+type constraintState struct {
+	constraint    Constraint
+	errorBias     float64
+	maxBias       float64
+	maxForce      float64
+	restoreParams func()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Restore brings the space back to the state saved in the snapshot.
+// Objects added to the space after the snapshot was taken are removed from it,
+// objects removed since then are added back.
+// Chipmunk doesn't allow to restore cached contacts, so they are dropped instead,
+// which makes every restore of the same snapshot continue the simulation identically.
+// Sleeping bodies are put back to sleep in the groups they were sleeping in.
+// Restore must not be called while the space is locked.
+func (s Space) Restore(snap *Snapshot) {
+	if snap.space != s {
+		panic("snapshot of another space in Restore()")
+	}
+
+	if s.IsLocked() {
+		panic("Restore() called while the space is locked")
+	}
+
+	s.removeAll()
+	snap.params.restore(s)
+
+	for i := range snap.bodies {
+		snap.bodies[i].restore(s)
+	}
+
+	for i := range snap.shapes {
+		snap.shapes[i].restore(s)
+	}
+
+	for i := range snap.constraints {
+		snap.constraints[i].restore(s)
+	}
+
+	s.ReindexStatic()
+
+	// bodies which were asleep together are put to sleep in the same group,
+	// so they wake up together
+	groups := make(map[Body]Body)
+	for i := range snap.bodies {
+		bs := &snap.bodies[i]
+
+		if !bs.inSpace || bs.body.IsStatic() {
+			continue
+		}
+
+		if bs.sleeping {
+			if bs.body.IsSleeping() {
+				continue
+			}
+			if g, ok := groups[bs.sleepGroup]; ok {
+				bs.body.SleepWithGroup(g)
+			} else {
+				bs.body.Sleep()
+				groups[bs.sleepGroup] = bs.body
+			}
+		} else {
+			bs.body.c().node_private.idleTime = C.cpFloat(bs.idleTime)
+		}
+	}
+}
+
+// Snapshot saves the state of the space and every object in it.
+func (s Space) Snapshot() *Snapshot {
+	snap := &Snapshot{space: s}
+	snap.params.save(s)
+
+	seen := make(map[Body]bool)
+	saveBody := func(b Body, inSpace bool) {
+		if b == nullBody || seen[b] {
+			return
+		}
+
+		seen[b] = true
+		snap.bodies = append(snap.bodies, saveBodyState(b, inSpace))
+	}
+
+	s.EachBody(func(b Body) {
+		saveBody(b, true)
+	})
+
+	s.EachShape(func(sh Shape) {
+		saveBody(sh.Body(), false)
+		snap.shapes = append(snap.shapes, saveShapeState(sh))
+	})
+
+	s.EachConstraint(func(c Constraint) {
+		saveBody(c.A(), false)
+		saveBody(c.B(), false)
+		snap.constraints = append(snap.constraints, saveConstraintState(c))
+	})
+
+	return snap
+}
+
+// removeAll removes every constraint, shape and body from the space.
+func (s Space) removeAll() {
+	var (
+		bodies      []Body
+		shapes      []Shape
+		constraints []Constraint
+	)
+
+	s.EachConstraint(func(c Constraint) {
+		constraints = append(constraints, c)
+	})
+
+	s.EachShape(func(sh Shape) {
+		shapes = append(shapes, sh)
+	})
+
+	s.EachBody(func(b Body) {
+		bodies = append(bodies, b)
+	})
+
+	for _, c := range constraints {
+		s.RemoveConstraint(c)
+	}
+
+	for _, sh := range shapes {
+		s.RemoveShape(sh)
+	}
+
+	for _, b := range bodies {
+		s.RemoveBody(b)
+	}
+}
+
+// save saves the simulation parameters of the space.
+func (p *spaceParams) save(s Space) {
+	p.collisionBias = s.CollisionBias()
+	p.collisionPersistence = s.CollisionPersistence()
+	p.collisionSlop = s.CollisionSlop()
+	p.damping = s.Damping()
+	p.enableContactGraph = s.EnableContactGraph()
+	p.gravity = s.Gravity()
+	p.idleSpeedThreshold = s.IdleSpeedThreshold()
+	p.iterations = s.Iterations()
+	p.sleepTimeThreshold = s.SleepTimeThreshold()
+}
+
+// restore restores the simulation parameters of the space.
+func (p *spaceParams) restore(s Space) {
+	s.SetCollisionBias(p.collisionBias)
+	s.SetCollisionPersistence(p.collisionPersistence)
+	s.SetCollisionSlop(p.collisionSlop)
+	s.SetDamping(p.damping)
+	s.SetEnableContactGraph(p.enableContactGraph)
+	s.SetGravity(p.gravity)
+	s.SetIdleSpeedThreshold(p.idleSpeedThreshold)
+	s.SetIterations(p.iterations)
+	s.SetSleepTimeThreshold(p.sleepTimeThreshold)
+}
+
+// saveBodyState saves the state of a body.
+func saveBodyState(b Body, inSpace bool) bodyState {
+	return bodyState{
+		body:                 b,
+		inSpace:              inSpace,
+		sleeping:             b.IsSleeping(),
+		sleepGroup:           cpBody(b.c().node_private.root),
+		idleTime:             float64(b.c().node_private.idleTime),
+		mass:                 b.Mass(),
+		moment:               b.Moment(),
+		position:             b.Position(),
+		velocity:             b.Velocity(),
+		force:                b.Force(),
+		angle:                b.Angle(),
+		angularVelocity:      b.AngularVelocity(),
+		torque:               b.Torque(),
+		velocityLimit:        b.VelocityLimit(),
+		angularVelocityLimit: b.AngularVelocityLimit(),
+	}
+}
+
+// restore restores the state of a body and adds it to the space if it was there.
+// Static bodies only get their position and velocity back.
+func (bs *bodyState) restore(s Space) {
+	b := bs.body
+
+	if !b.IsStatic() {
+		b.SetMass(bs.mass)
+		b.SetMoment(bs.moment)
+		b.SetForce(bs.force)
+		b.SetTorque(bs.torque)
+		b.SetVelocityLimit(bs.velocityLimit)
+		b.SetAngularVelocityLimit(bs.angularVelocityLimit)
+	}
+
+	b.SetPosition(bs.position)
+	b.SetAngle(bs.angle)
+	b.SetVelocity(bs.velocity)
+	b.SetAngularVelocity(bs.angularVelocity)
+
+	if bs.inSpace {
+		s.AddBody(b)
+	}
+}
+
+// saveShapeState saves the state of a shape.
+func saveShapeState(sh Shape) shapeState {
+	ss := shapeState{
+		shape:           sh,
+		body:            sh.Body(),
+		collisionType:   sh.CollisionType(),
+		elasticity:      sh.Elasticity(),
+		friction:        sh.Friction(),
+		group:           sh.Group(),
+		layers:          sh.Layers(),
+		sensor:          sh.Sensor(),
+		surfaceVelocity: sh.SurfaceVelocity(),
+	}
+
+	switch sh := sh.(type) {
+	case CircleShape:
+		offset, radius := sh.Offset(), sh.Radius()
+		ss.restoreGeometry = func() {
+			sh.SetOffset(offset)
+			sh.SetRadius(radius)
+		}
+
+	case SegmentShape:
+		a, b, radius := sh.A(), sh.B(), sh.Radius()
+		ss.restoreGeometry = func() {
+			sh.SetEndpoints(a, b)
+			sh.SetRadius(radius)
+		}
+
+	case PolyShape:
+		verts := sh.VertsLocal()
+		radius := sh.Radius()
+		ss.restoreGeometry = func() {
+			sh.SetVerts(verts, Origin())
+			sh.SetRadius(radius)
+		}
+	}
+
+	return ss
+}
+
+// restore restores the state of a shape and adds it to the space.
+func (ss *shapeState) restore(s Space) {
+	sh := ss.shape
+
+	sh.SetBody(ss.body)
+	sh.SetCollisionType(ss.collisionType)
+	sh.SetElasticity(ss.elasticity)
+	sh.SetFriction(ss.friction)
+	sh.SetGroup(ss.group)
+	sh.SetLayers(ss.layers)
+	sh.SetSensor(ss.sensor)
+	sh.SetSurfaceVelocity(ss.surfaceVelocity)
+
+	if ss.restoreGeometry != nil {
+		ss.restoreGeometry()
+	}
+
+	s.AddShape(sh)
+}
+
+// saveConstraintState saves the state of a constraint including
+// the accumulated impulse used by the solver for warm starting.
+func saveConstraintState(c Constraint) constraintState {
+	cs := constraintState{
+		constraint: c,
+		errorBias:  c.ErrorBias(),
+		maxBias:    c.MaxBias(),
+		maxForce:   c.MaxForce(),
+	}
+
+	p := unsafe.Pointer(c.c())
+
+	switch c := c.(type) {
+	case DampedRotarySpring:
+		j := (*C.cpDampedRotarySpring)(p)
+		restAngle, stiffness, damping, jAcc := c.RestAngle(), c.Stiffness(), c.Damping(), j.jAcc
+		cs.restoreParams = func() {
+			c.SetRestAngle(restAngle)
+			c.SetStiffness(stiffness)
+			c.SetDamping(damping)
+			j.jAcc = jAcc
+		}
+
+	case DampedSpring:
+		j := (*C.cpDampedSpring)(p)
+		anchr1, anchr2, jAcc := c.Anchr1(), c.Anchr2(), j.jAcc
+		restLength, stiffness, damping := c.RestLength(), c.Stiffness(), c.Damping()
+		cs.restoreParams = func() {
+			c.SetAnchr1(anchr1)
+			c.SetAnchr2(anchr2)
+			c.SetRestLength(restLength)
+			c.SetStiffness(stiffness)
+			c.SetDamping(damping)
+			j.jAcc = jAcc
+		}
+
+	case GearJoint:
+		j := (*C.cpGearJoint)(p)
+		phase, ratio, jAcc := c.Phase(), c.Ratio(), j.jAcc
+		cs.restoreParams = func() {
+			c.SetPhase(phase)
+			c.SetRatio(ratio)
+			j.jAcc = jAcc
+		}
+
+	case GrooveJoint:
+		j := (*C.cpGrooveJoint)(p)
+		grooveA, grooveB, anchr2, jAcc := c.GrooveA(), c.GrooveB(), c.Anchr2(), j.jAcc
+		cs.restoreParams = func() {
+			c.SetGrooveA(grooveA)
+			c.SetGrooveB(grooveB)
+			c.SetAnchr2(anchr2)
+			j.jAcc = jAcc
+		}
+
+	case PinJoint:
+		j := (*C.cpPinJoint)(p)
+		anchr1, anchr2, dist, jnAcc := c.Anchr1(), c.Anchr2(), c.Dist(), j.jnAcc
+		cs.restoreParams = func() {
+			c.SetAnchr1(anchr1)
+			c.SetAnchr2(anchr2)
+			c.SetDist(dist)
+			j.jnAcc = jnAcc
+		}
+
+	case PivotJoint:
+		j := (*C.cpPivotJoint)(p)
+		anchr1, anchr2, jAcc := c.Anchr1(), c.Anchr2(), j.jAcc
+		cs.restoreParams = func() {
+			c.SetAnchr1(anchr1)
+			c.SetAnchr2(anchr2)
+			j.jAcc = jAcc
+		}
+
+	case RatchetJoint:
+		j := (*C.cpRatchetJoint)(p)
+		angle, phase, ratchet, jAcc := c.Angle(), c.Phase(), c.Ratchet(), j.jAcc
+		cs.restoreParams = func() {
+			c.SetAngle(angle)
+			c.SetPhase(phase)
+			c.SetRatchet(ratchet)
+			j.jAcc = jAcc
+		}
+
+	case RotaryLimitJoint:
+		j := (*C.cpRotaryLimitJoint)(p)
+		min, max, jAcc := c.Min(), c.Max(), j.jAcc
+		cs.restoreParams = func() {
+			c.SetMin(min)
+			c.SetMax(max)
+			j.jAcc = jAcc
+		}
+
+	case SimpleMotor:
+		j := (*C.cpSimpleMotor)(p)
+		rate, jAcc := c.Rate(), j.jAcc
+		cs.restoreParams = func() {
+			c.SetRate(rate)
+			j.jAcc = jAcc
+		}
+
+	case SlideJoint:
+		j := (*C.cpSlideJoint)(p)
+		anchr1, anchr2, min, max, jnAcc := c.Anchr1(), c.Anchr2(), c.Min(), c.Max(), j.jnAcc
+		cs.restoreParams = func() {
+			c.SetAnchr1(anchr1)
+			c.SetAnchr2(anchr2)
+			c.SetMin(min)
+			c.SetMax(max)
+			j.jnAcc = jnAcc
+		}
+	}
+
+	return cs
+}
+
+// restore restores the state of a constraint and adds it to the space.
+func (cs *constraintState) restore(s Space) {
+	c := cs.constraint
+
+	c.SetErrorBias(cs.errorBias)
+	c.SetMaxBias(cs.maxBias)
+	c.SetMaxForce(cs.maxForce)
+
+	if cs.restoreParams != nil {
+		cs.restoreParams()
+	}
+
+	s.AddConstraint(c)
+}
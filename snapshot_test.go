@@ -0,0 +1,154 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_SnapshotRestoreDeterministic(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)
+	ground.SetFriction(0.7)
+	s.AddShape(ground)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 5.0))
+	b.SetVelocity(VectNew(3.0, 0.0))
+	ball := CircleShapeNew(b, 1.0, Origin())
+	ball.SetFriction(0.7)
+	s.AddShape(ball)
+
+	pin := PinJointNew(s.StaticBody(), b, VectNew(0.0, 8.0), Origin())
+	s.AddConstraint(pin)
+
+	for i := 0; i < 30; i++ {
+		s.Step(1.0 / 60.0)
+	}
+
+	snap := s.Snapshot()
+	pos, angle := b.Position(), b.Angle()
+
+	run := func() (Vect, float64) {
+		s.Restore(snap)
+		assert.Equal(t, pos, b.Position())
+		assert.Equal(t, angle, b.Angle())
+
+		for i := 0; i < 60; i++ {
+			s.Step(1.0 / 60.0)
+		}
+
+		return b.Position(), b.Angle()
+	}
+
+	p1, a1 := run()
+	p2, a2 := run()
+
+	assert.NotEqual(t, pos, p1)
+	assert.Equal(t, p1, p2)
+	assert.Equal(t, a1, a2)
+
+	s.FreeChildren()
+	s.Step(1.0 / 60.0)
+	s.Free()
+}
+
+func Test_SnapshotRestoreMembership(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)
+	ground.SetFriction(0.7)
+	s.AddShape(ground)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 5.0))
+	b.SetVelocity(VectNew(3.0, 0.0))
+	ball := CircleShapeNew(b, 1.0, Origin())
+	ball.SetFriction(0.7)
+	s.AddShape(ball)
+
+	pin := PinJointNew(s.StaticBody(), b, VectNew(0.0, 8.0), Origin())
+	s.AddConstraint(pin)
+
+	snap := s.Snapshot()
+
+	b2 := s.AddBody(BodyNew(1.0, 1.0))
+	s.RemoveConstraint(pin)
+	s.RemoveShape(ball)
+	ball.SetFriction(0.0)
+	pin.SetDist(1.0)
+
+	s.Restore(snap)
+
+	assert.T(t, s.Contains(b))
+	assert.T(t, s.Contains(ball))
+	assert.T(t, s.Contains(pin))
+	assert.T(t, !s.Contains(b2))
+	assert.Equal(t, 0.7, ball.Friction())
+	assert.Equal(t, 3.0, pin.Dist())
+
+	b2.Free()
+	s.FreeChildren()
+	s.Step(1.0 / 60.0)
+	s.Free()
+}
+
+func Test_SnapshotRestoreSleepingGroup(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+	s.SetSleepTimeThreshold(0.5)
+
+	ground := SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0)
+	ground.SetFriction(0.7)
+	s.AddShape(ground)
+
+	var boxes []Body
+	for _, y := range []float64{1.0, 3.0} {
+		b := s.AddBody(BodyNew(1.0, MomentForBox(1.0, 2.0, 2.0)))
+		b.SetPosition(VectNew(0.0, y))
+		box := s.AddShape(BoxShapeNew(b, 2.0, 2.0))
+		box.SetFriction(0.7)
+		boxes = append(boxes, b)
+	}
+
+	for i := 0; i < 300; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.T(t, boxes[0].IsSleeping() && boxes[1].IsSleeping())
+
+	s.Restore(s.Snapshot())
+	assert.T(t, boxes[0].IsSleeping() && boxes[1].IsSleeping())
+
+	// the stacked boxes still wake up together
+	boxes[1].Activate()
+	assert.T(t, !boxes[0].IsSleeping())
+
+	s.FreeChildren()
+	s.Step(1.0 / 60.0)
+	s.Free()
+}
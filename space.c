@@ -31,19 +31,15 @@ extern void nearestPointQuery(cpShape *s, cpFloat distance, cpVect point, void *
 extern void pointQuery(cpShape *s, void *p);
 extern void postStep(cpSpace *space, cpDataPointer key, cpDataPointer data);
 extern void segmentQuery(cpShape *s, cpFloat t, cpVect n, void *p);
+extern void shapeQuery(cpShape *s, cpContactPointSet *points, void *p);
 
 extern cpBool begin(cpArbiter *arb, cpSpace *space, cpDataPointer data);
 extern cpBool preSolve(cpArbiter *arb, cpSpace *space, cpDataPointer data);
 extern void postSolve(cpArbiter *arb, cpSpace *space, cpDataPointer data);
 extern void separate(cpArbiter *arb, cpSpace *space, cpDataPointer data);
 
-extern cpBool beginDefault(cpArbiter *arb, cpSpace *space, cpDataPointer data);
-extern cpBool preSolveDefault(cpArbiter *arb, cpSpace *space, cpDataPointer data);
-extern void postSolveDefault(cpArbiter *arb, cpSpace *space, cpDataPointer data);
-extern void separateDefault(cpArbiter *arb, cpSpace *space, cpDataPointer data);
-
-inline cpBool space_add_poststep(cpSpace *space, cpDataPointer key, cpDataPointer data) {
-	return cpSpaceAddPostStepCallback(space, (void *)postStep, key, data);
+inline cpBool space_add_poststep(cpSpace *space, uintptr_t h) {
+	return cpSpaceAddPostStepCallback(space, (void *)postStep, (void *)h, (void *)h);
 }
 
 inline void space_add_collision_handler(cpSpace *space, cpCollisionType a, cpCollisionType b) {
@@ -52,38 +48,42 @@ inline void space_add_collision_handler(cpSpace *space, cpCollisionType a, cpCol
 }
 
 inline void space_set_default_collision_handler(cpSpace *space) {
-	cpSpaceSetDefaultCollisionHandler(space, (void *)beginDefault, (void *)preSolveDefault,
-		(void *)postSolveDefault, (void *)separateDefault, NULL);
+	cpSpaceSetDefaultCollisionHandler(space, (void *)begin, (void *)preSolve,
+		(void *)postSolve, (void *)separate, NULL);
 }
 
-inline void space_bb_query(cpSpace *space, cpBB bb, cpLayers layers, cpGroup group, void *f) {
-	cpSpaceBBQuery(space, bb, layers, group, bbQuery, f);
+inline void space_bb_query(cpSpace *space, cpBB bb, cpLayers layers, cpGroup group, uintptr_t h) {
+	cpSpaceBBQuery(space, bb, layers, group, bbQuery, (void *)h);
 }
 
-inline void space_each_body(cpSpace *space, void *f) {
-	cpSpaceEachBody(space, eachBodySpace, f);
+inline void space_each_body(cpSpace *space, uintptr_t h) {
+	cpSpaceEachBody(space, eachBodySpace, (void *)h);
 }
 
-inline void space_each_constraint(cpSpace *space, void *f) {
-	cpSpaceEachConstraint(space, eachConstraintSpace, f);
+inline void space_each_constraint(cpSpace *space, uintptr_t h) {
+	cpSpaceEachConstraint(space, eachConstraintSpace, (void *)h);
 }
 
-inline void space_each_shape(cpSpace *space, void *f) {
-	cpSpaceEachShape(space, eachShapeSpace, f);
+inline void space_each_shape(cpSpace *space, uintptr_t h) {
+	cpSpaceEachShape(space, eachShapeSpace, (void *)h);
 }
 
 inline void space_nearest_point_query(cpSpace *space, cpVect point, cpFloat maxDistance,
-	cpLayers layers, cpGroup group, void *f) {
+	cpLayers layers, cpGroup group, uintptr_t h) {
 
-	cpSpaceNearestPointQuery(space, point, maxDistance, layers, group, nearestPointQuery, f);
+	cpSpaceNearestPointQuery(space, point, maxDistance, layers, group, nearestPointQuery, (void *)h);
 }
 
-inline void space_point_query(cpSpace *s, cpVect point, cpLayers layers, cpGroup group, void *p) {
-	cpSpacePointQuery(s, point, layers, group, pointQuery, p);
+inline void space_point_query(cpSpace *s, cpVect point, cpLayers layers, cpGroup group, uintptr_t h) {
+	cpSpacePointQuery(s, point, layers, group, pointQuery, (void *)h);
 }
 
 inline void space_segment_query(cpSpace *space, cpVect start, cpVect end, cpLayers layers,
-	cpGroup group, void *f) {
+	cpGroup group, uintptr_t h) {
+
+	cpSpaceSegmentQuery(space, start, end, layers, group, segmentQuery, (void *)h);
+}
 
-	cpSpaceSegmentQuery(space, start, end, layers, group, segmentQuery, f);
+inline cpBool space_shape_query(cpSpace *space, cpShape *shape, uintptr_t h) {
+	return cpSpaceShapeQuery(space, shape, shapeQuery, (void *)h);
 }
@@ -29,6 +29,9 @@ import "C"
 
 import (
 	"fmt"
+	"iter"
+	"runtime/cgo"
+	"sync"
 	"unsafe"
 )
 
@@ -46,15 +49,37 @@ type PointQuery func(s Shape)
 // SegmentQuery is a query callback function type.
 type SegmentQuery func(s Shape, t float64, n Vect)
 
+// ShapeQuery is a callback function type for ShapeQuery function.
+// It receives a shape overlapping the query shape and their contact points.
+type ShapeQuery func(s Shape, points []ContactPoint)
+
 // Space is a basic unit of simulation in Chipmunk.
+// Distinct spaces may be stepped concurrently from different goroutines,
+// but a single space and its objects must not be used by more than one goroutine at a time.
 type Space uintptr
 
+// spaceData is the Go side state of a space.
+// Its mutex guards every field, it must never be held while calling user code.
 type spaceData struct {
-	userData interface{}
+	mu sync.Mutex
+
+	collisionFilters      map[collisionTypePair][]collisionFilterEntry
+	collisionHandlers     map[collisionTypePair]collisionHandlerEntry
+	collisionEventStreams []*CollisionEventStream
+	pendingOps            []func()
+	postStepCallbacks     map[interface{}]cgo.Handle
+	time                  float64
+	userData              interface{}
 }
 
 var (
-	spaceDataMap = make(map[Space]*spaceData)
+	spaceDataMap   = make(map[Space]*spaceData)
+	spaceDataMutex sync.RWMutex
+)
+
+var (
+	pendingObjects      = make(map[unsafe.Pointer]pendingObject)
+	pendingObjectsMutex sync.Mutex
 )
 
 // SpaceObject is an interface every space object must implement.
@@ -65,27 +90,21 @@ type SpaceObject interface {
 	removeFromSpace(Space)
 }
 
-// collisionTypePair is a pair of collision types
-type collisionTypePair struct {
-	a, b CollisionType
-}
-
-// collisionHandler is a set of collision handler functions and optional user data
-type collisionHandler struct {
-	beginFunc    func(Space, Arbiter, interface{}) bool
-	preSolveFunc func(Space, Arbiter, interface{}) bool
-	postStepFunc func(Space, Arbiter, interface{})
-	separateFunc func(Space, Arbiter, interface{})
-	data         interface{}
+// pendingObject is the space an object has deferred operations in and their number.
+type pendingObject struct {
+	space Space
+	ops   int
 }
 
-////////////////////////////////////////////////////////////////////////////////
+// pendingOpsKey is the post-step callback key applying the operations
+// deferred while the space is locked.
+type pendingOpsKey struct{}
 
-var (
-	postStepCallbackMap        = make(map[Space]map[interface{}]func(Space, interface{}))
-	collisionHandlerMap        = make(map[Space]map[collisionTypePair]collisionHandler)
-	defaultCollisionHandlerMap = make(map[Space]collisionHandler)
-)
+// postStepCallback is a scheduled post-step callback function and its key.
+type postStepCallback struct {
+	f   func(Space, interface{})
+	key interface{}
+}
 
 ////////////////////////////////////////////////////////////////////////////////
 
@@ -102,12 +121,20 @@ func (s Space) Add(obj SpaceObject) SpaceObject {
 }
 
 // AddBody adds a rigid body to the simulation.
+// If the space is locked, the body is added when the space is unlocked.
 func (s Space) AddBody(b Body) Body {
+	if s.deferOp(unsafe.Pointer(b.c()), func() { C.cpSpaceAddBody(s.c(), b.c()) }) {
+		return b
+	}
 	return cpBody(C.cpSpaceAddBody(s.c(), b.c()))
 }
 
 // AddConstraint adds a constraint to the simulation.
+// If the space is locked, the constraint is added when the space is unlocked.
 func (s Space) AddConstraint(c Constraint) Constraint {
+	if s.deferOp(unsafe.Pointer(c.c()), func() { C.cpSpaceAddConstraint(s.c(), c.c()) }) {
+		return c
+	}
 	return cpConstraint(C.cpSpaceAddConstraint(s.c(), c.c()))
 }
 
@@ -115,43 +142,56 @@ func (s Space) AddConstraint(c Constraint) Constraint {
 // You can only register one callback per unique value for key.
 // Returns true only if the key has never been scheduled before.
 func (s Space) AddPostStepCallback(f func(Space, interface{}), key interface{}) bool {
-	postStepCallbackMap[s][key] = f
-	return cpBool(C.space_add_poststep(s.c(), dataToC(key), dataToC(f)))
+	d := s.data()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.postStepCallbacks[key]; ok {
+		return false
+	}
+
+	h := handleNew(postStepCallback{f, key})
+	d.postStepCallbacks[key] = h
+	return cpBool(C.space_add_poststep(s.c(), handleToC(h)))
 }
 
 // AddCollisionHandler sets a collision handler to be used whenever the two shapes with the
-// given collision types collide
+// given collision types collide. The data is passed to every function, nil functions are
+// skipped. It's a shorthand for SetCollisionHandler.
 func (s Space) AddCollisionHandler(a, b CollisionType,
 	beginFunc, preSolveFunc func(Space, Arbiter, interface{}) bool,
 	postStepFunc, separateFunc func(Space, Arbiter, interface{}), data interface{}) {
 
-	colTypes := collisionTypePair{a, b}
-	handler := collisionHandler{beginFunc, preSolveFunc, postStepFunc, separateFunc, data}
-	collisionHandlerMap[s][colTypes] = handler
-
-	C.space_add_collision_handler(s.c(), a.c(), b.c())
+	s.SetCollisionHandler(a, b, collisionHandler{beginFunc, preSolveFunc, postStepFunc, separateFunc, data})
 }
 
 // SetDefaultCollisionHandler sets a default collision handler for this space.  The default
 // collision handler is invoked for each colliding pair of shapes that isn't explicitly handled
-// by a specific collision handler.  You can pass NULL for any function you don't want to
-// implement.
+// by a specific collision handler.  You can pass nil for any function you don't want to
+// implement. It's a shorthand for SetCollisionHandler(AnyCollisionType, AnyCollisionType, ...).
 func (s Space) SetDefaultCollisionHandler(beginFunc, preSolveFunc func(Space, Arbiter, interface{}) bool,
 	postStepFunc, separateFunc func(Space, Arbiter, interface{}), data interface{}) {
 
-	handler := collisionHandler{beginFunc, preSolveFunc, postStepFunc, separateFunc, data}
-	defaultCollisionHandlerMap[s] = handler
-	C.space_set_default_collision_handler(s.c())
+	s.SetCollisionHandler(AnyCollisionType, AnyCollisionType,
+		collisionHandler{beginFunc, preSolveFunc, postStepFunc, separateFunc, data})
 }
 
 // AddShape adds a collision shape to the simulation.
 // If the shape is attached to a static body, it will be added as a static shape.
+// If the space is locked, the shape is added when the space is unlocked.
 func (s Space) AddShape(sh Shape) Shape {
+	if s.deferOp(unsafe.Pointer(sh.c()), func() { C.cpSpaceAddShape(s.c(), sh.c()) }) {
+		return sh
+	}
 	return cpShape(C.cpSpaceAddShape(s.c(), sh.c()))
 }
 
 // AddStaticShape explicity adds a shape as a static shape to the simulation.
+// If the space is locked, the shape is added when the space is unlocked.
 func (s Space) AddStaticShape(sh Shape) Shape {
+	if s.deferOp(unsafe.Pointer(sh.c()), func() { C.cpSpaceAddStaticShape(s.c(), sh.c()) }) {
+		return sh
+	}
 	return cpShape(C.cpSpaceAddStaticShape(s.c(), sh.c()))
 }
 
@@ -159,7 +199,27 @@ func (s Space) AddStaticShape(sh Shape) Shape {
 // function for each shape found.
 // Only the shape's bounding boxes are checked for overlap, not their full shape.
 func (s Space) BBQuery(bb BB, layers Layers, group Group, f BBQuery) {
-	C.space_bb_query(s.c(), bb.c(), layers.c(), group.c(), unsafe.Pointer(&f))
+	h := handleNew(f)
+	defer handleDelete(h)
+	C.space_bb_query(s.c(), bb.c(), layers.c(), group.c(), handleToC(h))
+}
+
+// Bodies returns an iterator over the bodies in the space.
+// The bodies are collected before the first one is yielded, so the loop may
+// break early and add or remove objects without the space being locked.
+func (s Space) Bodies() iter.Seq[Body] {
+	return func(yield func(Body) bool) {
+		var bodies []Body
+		s.EachBody(func(b Body) {
+			bodies = append(bodies, b)
+		})
+
+		for _, b := range bodies {
+			if !yield(b) {
+				return
+			}
+		}
+	}
 }
 
 // CollisionBias returns the speed of how fast overlapping shapes are pushed apart.
@@ -182,6 +242,24 @@ func (s Space) Contains(obj SpaceObject) bool {
 	return obj.containedInSpace(s)
 }
 
+// Constraints returns an iterator over the constraints in the space.
+// The constraints are collected before the first one is yielded, so the loop may
+// break early and add or remove objects without the space being locked.
+func (s Space) Constraints() iter.Seq[Constraint] {
+	return func(yield func(Constraint) bool) {
+		var constraints []Constraint
+		s.EachConstraint(func(c Constraint) {
+			constraints = append(constraints, c)
+		})
+
+		for _, c := range constraints {
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
 // CurrentTimeStep returns the current (if you are in a callback from SpaceStep())
 // or most recent (outside of a SpaceStep() call) timestep.
 func (s Space) CurrentTimeStep() float64 {
@@ -194,6 +272,7 @@ func (s Space) Damping() float64 {
 }
 
 // Each calls a callback function on each object of specific type (according to iterator) in the space.
+// Bodies, Constraints and Shapes are typed alternatives which can stop early.
 func (s Space) Each(iter interface{}) {
 	switch f := iter.(type) {
 	case func(Body):
@@ -209,20 +288,23 @@ func (s Space) Each(iter interface{}) {
 
 // EachBody calls a callback function on each body in the space.
 func (s Space) EachBody(iter func(Body)) {
-	p := unsafe.Pointer(&iter)
-	C.space_each_body(s.c(), p)
+	h := handleNew(iter)
+	defer handleDelete(h)
+	C.space_each_body(s.c(), handleToC(h))
 }
 
 // EachConstraint calls a callback function on each constraint in the space.
 func (s Space) EachConstraint(iter func(Constraint)) {
-	p := unsafe.Pointer(&iter)
-	C.space_each_constraint(s.c(), p)
+	h := handleNew(iter)
+	defer handleDelete(h)
+	C.space_each_constraint(s.c(), handleToC(h))
 }
 
 // EachShape calls a callback function on each shape in the space.
 func (s Space) EachShape(iter func(Shape)) {
-	p := unsafe.Pointer(&iter)
-	C.space_each_shape(s.c(), p)
+	h := handleNew(iter)
+	defer handleDelete(h)
+	C.space_each_shape(s.c(), handleToC(h))
 }
 
 // EnableContactGraph returns true if rebuild of the contact graph during each step is enabled.
@@ -232,11 +314,30 @@ func (s Space) EnableContactGraph() bool {
 
 // Free removes a space.
 func (s Space) Free() {
-	delete(spaceDataMap, s)
-	delete(postStepCallbackMap, s)
-	delete(collisionHandlerMap, s)
-	delete(defaultCollisionHandlerMap, s)
 	C.cpSpaceFree(s.c())
+
+	spaceDataMutex.Lock()
+	d := spaceDataMap[s]
+	delete(spaceDataMap, s)
+	spaceDataMutex.Unlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, h := range d.postStepCallbacks {
+		handleDelete(h)
+	}
+
+	d.pendingOps = nil
+	d.closeCollisionEventStreams()
+
+	pendingObjectsMutex.Lock()
+	for obj, p := range pendingObjects {
+		if p.space == s {
+			delete(pendingObjects, obj)
+		}
+	}
+	pendingObjectsMutex.Unlock()
 }
 
 // FreeChildren frees all bodies, constraints and shapes in the space.
@@ -259,6 +360,16 @@ func (s Space) FreeChildren() {
 	})
 }
 
+// Flush applies the additions and removals deferred while the space was locked,
+// in the order they were requested. It does nothing if the space is still locked,
+// they are applied when the space is unlocked anyway.
+// After Step returns, there is never anything to flush.
+func (s Space) Flush() {
+	if !s.IsLocked() {
+		s.runPendingOps()
+	}
+}
+
 // Gravity returns current gravity used when integrating velocity for rigid bodies.
 func (s Space) Gravity() Vect {
 	return cpVect(C.cpSpaceGetGravity(s.c()))
@@ -283,13 +394,27 @@ func (s Space) Iterations() int {
 func (s Space) NearestPointQuery(point Vect, maxDistance float64, layers Layers, group Group,
 	f NearestPointQuery) {
 
+	h := handleNew(f)
+	defer handleDelete(h)
 	C.space_nearest_point_query(s.c(), point.c(), C.cpFloat(maxDistance), layers.c(), group.c(),
-		unsafe.Pointer(&f))
+		handleToC(h))
+}
+
+// NearestPointQueryNearest queries the space at a point and returns the nearest shape
+// within maxDistance. The Shape of the result is nil if no shape was found.
+func (s Space) NearestPointQueryNearest(point Vect, maxDistance float64, layers Layers,
+	group Group) NearestPointQueryInfo {
+
+	var out C.cpNearestPointQueryInfo
+	C.cpSpaceNearestPointQueryNearest(s.c(), point.c(), C.cpFloat(maxDistance), layers.c(), group.c(), &out)
+	return NearestPointQueryInfo{Shape: cpShape(out.shape), P: cpVect(out.p), D: float64(out.d), G: cpVect(out.g)}
 }
 
 // PointQuery queries the space at a point and calls a callback function for each shape found.
 func (s Space) PointQuery(point Vect, layers Layers, group Group, f PointQuery) {
-	C.space_point_query(s.c(), point.c(), layers.c(), group.c(), unsafe.Pointer(&f))
+	h := handleNew(f)
+	defer handleDelete(h)
+	C.space_point_query(s.c(), point.c(), layers.c(), group.c(), handleToC(h))
 }
 
 // PointQueryFirst queries the space at a point and returns
@@ -306,7 +431,18 @@ func (s Space) Remove(obj SpaceObject) {
 // SegmentQuery performs a directed line segment query (like a raycast)
 // against the space calling a callback function for each shape intersected.
 func (s Space) SegmentQuery(start, end Vect, layers Layers, group Group, f SegmentQuery) {
-	C.space_segment_query(s.c(), start.c(), end.c(), layers.c(), group.c(), unsafe.Pointer(&f))
+	h := handleNew(f)
+	defer handleDelete(h)
+	C.space_segment_query(s.c(), start.c(), end.c(), layers.c(), group.c(), handleToC(h))
+}
+
+// SegmentQueryFirst performs a directed line segment query (like a raycast)
+// against the space and returns the first shape hit.
+// The Shape of the result is nil if no shape was hit.
+func (s Space) SegmentQueryFirst(start, end Vect, layers Layers, group Group) SegmentQueryInfo {
+	var out C.cpSegmentQueryInfo
+	C.cpSpaceSegmentQueryFirst(s.c(), start.c(), end.c(), layers.c(), group.c(), &out)
+	return SegmentQueryInfo{Shape: cpShape(out.shape), T: float64(out.t), N: cpVect(out.n)}
 }
 
 // SetGravity sets the gravity to pass to rigid bodies when integrating velocity.
@@ -373,7 +509,28 @@ func (s Space) SetSleepTimeThreshold(t float64) {
 // Generally this points to your game's controller or game state
 // so you can access it when given a Space reference in a callback.
 func (s Space) SetUserData(data interface{}) {
-	spaceDataMap[s].userData = data
+	d := s.data()
+	d.mu.Lock()
+	d.userData = data
+	d.mu.Unlock()
+}
+
+// Shapes returns an iterator over the shapes in the space.
+// The shapes are collected before the first one is yielded, so the loop may
+// break early and add or remove objects without the space being locked.
+func (s Space) Shapes() iter.Seq[Shape] {
+	return func(yield func(Shape) bool) {
+		var shapes []Shape
+		s.EachShape(func(sh Shape) {
+			shapes = append(shapes, sh)
+		})
+
+		for _, sh := range shapes {
+			if !yield(sh) {
+				return
+			}
+		}
+	}
 }
 
 // SleepTimeThreshold returns the time a groups of bodies must remain idle in order to "fall asleep".
@@ -384,9 +541,16 @@ func (s Space) SleepTimeThreshold() float64 {
 // SpaceNew creates a new space.
 func SpaceNew() Space {
 	s := Space(unsafe.Pointer(C.cpSpaceNew()))
-	spaceDataMap[s] = &spaceData{}
-	postStepCallbackMap[s] = make(map[interface{}]func(Space, interface{}))
-	collisionHandlerMap[s] = make(map[collisionTypePair]collisionHandler)
+	d := &spaceData{
+		collisionFilters:  make(map[collisionTypePair][]collisionFilterEntry),
+		collisionHandlers: make(map[collisionTypePair]collisionHandlerEntry),
+		postStepCallbacks: make(map[interface{}]cgo.Handle),
+	}
+
+	spaceDataMutex.Lock()
+	spaceDataMap[s] = d
+	spaceDataMutex.Unlock()
+
 	return s
 }
 
@@ -417,9 +581,38 @@ func (s Space) ConvertBodyToDynamic(b Body, m, i float64) {
 	C.cpSpaceConvertBodyToDynamic(s.c(), b.c(), C.cpFloat(m), C.cpFloat(i))
 }
 
+// ShapeQuery queries the space for shapes overlapping a shape and calls a callback
+// function with the contact points for each of them. The shape doesn't need to be
+// in the space; its bounding box is updated from its body first.
+// Returns true if any shapes were found.
+func (s Space) ShapeQuery(shape Shape, f ShapeQuery) bool {
+	shape.CacheBB()
+
+	h := handleNew(f)
+	defer handleDelete(h)
+	return cpBool(C.space_shape_query(s.c(), shape.c(), handleToC(h)))
+}
+
 // Step makes the space step forward in time by dt seconds.
+// Collision events recorded during the step are delivered when it's done.
 func (s Space) Step(dt float64) {
 	C.cpSpaceStep(s.c(), C.cpFloat(dt))
+
+	d := s.data()
+	d.mu.Lock()
+	d.time += dt
+	d.mu.Unlock()
+
+	s.flushCollisionEvents()
+}
+
+// Time returns the simulated time, the sum of the time steps done so far.
+// Within a step it's the time the step started at.
+func (s Space) Time() float64 {
+	d := s.data()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.time
 }
 
 // ReindexShape updates the collision detection data for a specific shape in the space.
@@ -438,35 +631,59 @@ func (s Space) ReindexStatic() {
 }
 
 // RemoveBody removes a rigid body from the simulation.
+// If the space is locked, the body is removed when the space is unlocked.
 func (s Space) RemoveBody(b Body) {
-	C.cpSpaceRemoveBody(s.c(), b.c())
+	if !s.deferOp(unsafe.Pointer(b.c()), func() { C.cpSpaceRemoveBody(s.c(), b.c()) }) {
+		C.cpSpaceRemoveBody(s.c(), b.c())
+	}
 }
 
-// RemoveCollisionHandler unsets a collision handler.
+// RemoveCollisionHandler unsets a collision handler set for a pair of collision types
+// in either order. It may be called while the space is locked.
 func (s Space) RemoveCollisionHandler(a CollisionType, b CollisionType) {
-	colTypes := collisionTypePair{a, b}
-	delete(collisionHandlerMap[s], colTypes)
-	C.cpSpaceRemoveCollisionHandler(s.c(), C.cpCollisionType(a), C.cpCollisionType(b))
+	key := collisionKey(a, b)
+
+	d := s.data()
+	d.mu.Lock()
+	_, ok := d.collisionHandlers[key]
+	delete(d.collisionHandlers, key)
+	d.mu.Unlock()
+
+	if ok {
+		s.syncCollisionHandler(key)
+	}
 }
 
 // RemoveConstraint removes a constraint from the simulation.
+// If the space is locked, the constraint is removed when the space is unlocked.
 func (s Space) RemoveConstraint(c Constraint) {
-	C.cpSpaceRemoveConstraint(s.c(), c.c())
+	if !s.deferOp(unsafe.Pointer(c.c()), func() { C.cpSpaceRemoveConstraint(s.c(), c.c()) }) {
+		C.cpSpaceRemoveConstraint(s.c(), c.c())
+	}
 }
 
 // RemoveShape removes a collision shape from the simulation.
+// If the space is locked, the shape is removed when the space is unlocked.
 func (s Space) RemoveShape(sh Shape) {
-	C.cpSpaceRemoveShape(s.c(), sh.c())
+	if !s.deferOp(unsafe.Pointer(sh.c()), func() { C.cpSpaceRemoveShape(s.c(), sh.c()) }) {
+		C.cpSpaceRemoveShape(s.c(), sh.c())
+	}
 }
 
 // RemoveStaticShape removes a collision shape added using AddStaticShape() from the simulation.
+// If the space is locked, the shape is removed when the space is unlocked.
 func (s Space) RemoveStaticShape(sh Shape) {
-	C.cpSpaceRemoveStaticShape(s.c(), sh.c())
+	if !s.deferOp(unsafe.Pointer(sh.c()), func() { C.cpSpaceRemoveStaticShape(s.c(), sh.c()) }) {
+		C.cpSpaceRemoveStaticShape(s.c(), sh.c())
+	}
 }
 
 // UserData returns user defined data.
 func (s Space) UserData() interface{} {
-	return spaceDataMap[s].userData
+	d := s.data()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.userData
 }
 
 // UseSpatialHash switches the space to use a spatial has as it's spatial index.
@@ -476,10 +693,17 @@ func (s Space) UseSpatialHash(dim float64, count int) {
 
 //export bbQuery
 func bbQuery(s *C.cpShape, p unsafe.Pointer) {
-	f := *(*BBQuery)(p)
+	f := cpHandleValue(p).(BBQuery)
 	f(cpShape(s))
 }
 
+// data returns the Go side state of the space.
+func (s Space) data() *spaceData {
+	spaceDataMutex.RLock()
+	defer spaceDataMutex.RUnlock()
+	return spaceDataMap[s]
+}
+
 // c converts Space to c.cpSpace pointer.
 func (s Space) c() *C.cpSpace {
 	return (*C.cpSpace)(unsafe.Pointer(s))
@@ -490,24 +714,80 @@ func cpSpace(s *C.cpSpace) Space {
 	return Space(uintptr(unsafe.Pointer(s)))
 }
 
+// deferOp queues an operation on an object to be applied when the space is unlocked.
+// Returns false without queueing it if the space isn't locked.
+func (s Space) deferOp(obj unsafe.Pointer, op func()) bool {
+	if !s.IsLocked() {
+		return false
+	}
+
+	pendingObjectsMutex.Lock()
+	p := pendingObjects[obj]
+	pendingObjects[obj] = pendingObject{s, p.ops + 1}
+	pendingObjectsMutex.Unlock()
+
+	d := s.data()
+	d.mu.Lock()
+	d.pendingOps = append(d.pendingOps, func() {
+		donePendingOp(obj)
+		op()
+	})
+	d.mu.Unlock()
+
+	s.AddPostStepCallback(func(s Space, _ interface{}) {
+		s.runPendingOps()
+	}, pendingOpsKey{})
+	return true
+}
+
+// donePendingOp forgets an operation deferred on an object.
+func donePendingOp(obj unsafe.Pointer) {
+	pendingObjectsMutex.Lock()
+	defer pendingObjectsMutex.Unlock()
+
+	p := pendingObjects[obj]
+	if p.ops--; p.ops > 0 {
+		pendingObjects[obj] = p
+	} else {
+		delete(pendingObjects, obj)
+	}
+}
+
 //export eachBodySpace
 func eachBodySpace(b *C.cpBody, p unsafe.Pointer) {
-	f := *(*func(Body))(p)
+	f := cpHandleValue(p).(func(Body))
 	f(cpBody(b))
 }
 
 //export eachConstraintSpace
 func eachConstraintSpace(c *C.cpConstraint, p unsafe.Pointer) {
-	f := *(*func(Constraint))(p)
+	f := cpHandleValue(p).(func(Constraint))
 	f(cpConstraint(c))
 }
 
 //export eachShapeSpace
 func eachShapeSpace(sh *C.cpShape, p unsafe.Pointer) {
-	f := *(*func(Shape))(p)
+	f := cpHandleValue(p).(func(Shape))
 	f(cpShape(sh))
 }
 
+// freeAfterPendingOps frees an object after the operations deferred on it,
+// so one removed from a locked space can be freed right away.
+// If the space is still locked, freeing the object is deferred as well.
+func freeAfterPendingOps(obj unsafe.Pointer, free func()) {
+	pendingObjectsMutex.Lock()
+	p, ok := pendingObjects[obj]
+	pendingObjectsMutex.Unlock()
+
+	switch {
+	case !ok:
+		free()
+	case !p.space.deferOp(obj, free):
+		p.space.runPendingOps()
+		free()
+	}
+}
+
 // freeObject frees an object.
 func (s Space) freeObject(obj SpaceObject) {
 	obj.Free()
@@ -515,125 +795,59 @@ func (s Space) freeObject(obj SpaceObject) {
 
 //export nearestPointQuery
 func nearestPointQuery(s *C.cpShape, distance C.cpFloat, point C.cpVect, p unsafe.Pointer) {
-	f := *(*NearestPointQuery)(p)
+	f := cpHandleValue(p).(NearestPointQuery)
 	f(cpShape(s), float64(distance), cpVect(point))
 }
 
 //export pointQuery
 func pointQuery(s *C.cpShape, p unsafe.Pointer) {
-	f := *(*PointQuery)(p)
+	f := cpHandleValue(p).(PointQuery)
 	f(cpShape(s))
 }
 
 //export postStep
 func postStep(s *C.cpSpace, p, data C.cpDataPointer) {
-	key := cpData(p)
-	f := cpData(data).(func(Space, interface{}))
-
-	// execute callback
-	f(cpSpace(s), key)
-	// remove from map
-	delete(postStepCallbackMap[Space(unsafe.Pointer(s))], key)
-}
-
-//export begin
-func begin(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) C.cpBool {
-	arb := cpArbiter(a)
-	space := cpSpace(s)
-	sa, sb := arb.Shapes()
-	colTypes := collisionTypePair{sa.CollisionType(), sb.CollisionType()}
-	handler := collisionHandlerMap[space][colTypes]
-	if handler.beginFunc == nil {
-		return boolToC(true)
-	}
-	return boolToC(handler.beginFunc(space, arb, handler.data))
-}
-
-//export preSolve
-func preSolve(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) C.cpBool {
-	arb := cpArbiter(a)
 	space := cpSpace(s)
-	sa, sb := arb.Shapes()
-	colTypes := collisionTypePair{sa.CollisionType(), sb.CollisionType()}
-	handler := collisionHandlerMap[space][colTypes]
-	if handler.preSolveFunc == nil {
-		return boolToC(true)
+	h := cpHandle(unsafe.Pointer(data))
+	cb := h.Value().(postStepCallback)
+
+	// remove from map before executing, so the callback may schedule the key again
+	d := space.data()
+	d.mu.Lock()
+	delete(d.postStepCallbacks, cb.key)
+	d.mu.Unlock()
+	handleDelete(h)
+
+	cb.f(space, cb.key)
+}
+
+// runPendingOps applies the queued operations in order.
+// An operation may queue more of them, e.g. from a separate callback, they are applied last.
+func (s Space) runPendingOps() {
+	d := s.data()
+	for {
+		d.mu.Lock()
+		if 0 == len(d.pendingOps) {
+			d.pendingOps = nil
+			d.mu.Unlock()
+			return
+		}
+		op := d.pendingOps[0]
+		d.pendingOps = d.pendingOps[1:]
+		d.mu.Unlock()
+
+		op()
 	}
-	return boolToC(handler.preSolveFunc(space, arb, handler.data))
-}
-
-//export postSolve
-func postSolve(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) {
-	arb := cpArbiter(a)
-	space := cpSpace(s)
-	sa, sb := arb.Shapes()
-	colTypes := collisionTypePair{sa.CollisionType(), sb.CollisionType()}
-	handler := collisionHandlerMap[space][colTypes]
-	if handler.postStepFunc == nil {
-		return
-	}
-	handler.postStepFunc(space, arb, handler.data)
-}
-
-//export separate
-func separate(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) {
-	arb := cpArbiter(a)
-	space := cpSpace(s)
-	sa, sb := arb.Shapes()
-	colTypes := collisionTypePair{sa.CollisionType(), sb.CollisionType()}
-	handler := collisionHandlerMap[space][colTypes]
-	if handler.separateFunc == nil {
-		return
-	}
-	handler.separateFunc(space, arb, handler.data)
-}
-
-//export beginDefault
-func beginDefault(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) C.cpBool {
-	arb := cpArbiter(a)
-	space := cpSpace(s)
-	handler := defaultCollisionHandlerMap[space]
-	if handler.beginFunc == nil {
-		return boolToC(true)
-	}
-	return boolToC(handler.beginFunc(space, arb, handler.data))
-}
-
-//export preSolveDefault
-func preSolveDefault(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) C.cpBool {
-	arb := cpArbiter(a)
-	space := cpSpace(s)
-	handler := defaultCollisionHandlerMap[space]
-	if handler.preSolveFunc == nil {
-		return boolToC(true)
-	}
-	return boolToC(handler.preSolveFunc(space, arb, handler.data))
-}
-
-//export postSolveDefault
-func postSolveDefault(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) {
-	arb := cpArbiter(a)
-	space := cpSpace(s)
-	handler := defaultCollisionHandlerMap[space]
-	if handler.postStepFunc == nil {
-		return
-	}
-	handler.postStepFunc(space, arb, handler.data)
-}
-
-//export separateDefault
-func separateDefault(a *C.cpArbiter, s *C.cpSpace, data C.cpDataPointer) {
-	arb := cpArbiter(a)
-	space := cpSpace(s)
-	handler := defaultCollisionHandlerMap[space]
-	if handler.separateFunc == nil {
-		return
-	}
-	handler.separateFunc(space, arb, handler.data)
 }
 
 //export segmentQuery
 func segmentQuery(s *C.cpShape, t C.cpFloat, n C.cpVect, p unsafe.Pointer) {
-	f := *(*SegmentQuery)(p)
+	f := cpHandleValue(p).(SegmentQuery)
 	f(cpShape(s), float64(t), cpVect(n))
 }
+
+//export shapeQuery
+func shapeQuery(s *C.cpShape, points *C.cpContactPointSet, p unsafe.Pointer) {
+	f := cpHandleValue(p).(ShapeQuery)
+	f(cpShape(s), cpContactPoints(points))
+}
@@ -24,17 +24,18 @@ WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 #ifndef _GOCHIPMUNK_SPACE_H
 #define _GOCHIPMUNK_SPACE_H
 
-inline cpBool space_add_poststep(cpSpace *space, cpDataPointer key, cpDataPointer data);
+inline cpBool space_add_poststep(cpSpace *space, uintptr_t h);
 inline void space_add_collision_handler(cpSpace *space, cpCollisionType a, cpCollisionType b);
 inline void space_set_default_collision_handler(cpSpace *space);
-inline void space_bb_query(cpSpace *space, cpBB bb, cpLayers layers, cpGroup group, void *f);
-inline void space_each_body(cpSpace *space, void *f);
-inline void space_each_constraint(cpSpace *space, void *f);
-inline void space_each_shape(cpSpace *space, void *f);
+inline void space_bb_query(cpSpace *space, cpBB bb, cpLayers layers, cpGroup group, uintptr_t h);
+inline void space_each_body(cpSpace *space, uintptr_t h);
+inline void space_each_constraint(cpSpace *space, uintptr_t h);
+inline void space_each_shape(cpSpace *space, uintptr_t h);
 inline void space_nearest_point_query(cpSpace *space, cpVect point, cpFloat maxDistance,
-	cpLayers layers, cpGroup group, void *f);
-inline void space_point_query(cpSpace *s, cpVect point, cpLayers layers, cpGroup group, void *p);
+	cpLayers layers, cpGroup group, uintptr_t h);
+inline void space_point_query(cpSpace *s, cpVect point, cpLayers layers, cpGroup group, uintptr_t h);
 inline void space_segment_query(cpSpace *space, cpVect start, cpVect end, cpLayers layers,
-	cpGroup group, void *f);
+	cpGroup group, uintptr_t h);
+inline cpBool space_shape_query(cpSpace *space, cpShape *shape, uintptr_t h);
 
 #endif // !_GOCHIPMUNK_SPACE_H
@@ -25,9 +25,22 @@ WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 
 import (
 	"github.com/bmizerany/assert"
+	"math"
+	"sync"
 	"testing"
 )
 
+// whileLocked calls a function while a space with bodies is locked.
+func whileLocked(s Space, f func()) {
+	called := false
+	s.EachBody(func(Body) {
+		if !called {
+			called = true
+			f()
+		}
+	})
+}
+
 func Test_SpaceNewFree(t *testing.T) {
 	s := SpaceNew()
 	assert.NotEqual(t, nil, s)
@@ -116,3 +129,329 @@ func Test_SpaceContains(t *testing.T) {
 
 	s.Free()
 }
+
+func Test_SpaceAddPostStepCallbackKey(t *testing.T) {
+	s := SpaceNew()
+	calls := 0
+	f := func(Space, interface{}) {
+		calls++
+	}
+
+	assert.T(t, s.AddPostStepCallback(f, "key"))
+	assert.T(t, !s.AddPostStepCallback(f, "key"))
+	s.Step(0.1)
+	assert.Equal(t, 1, calls)
+
+	assert.T(t, s.AddPostStepCallback(f, "key"))
+	s.Step(0.1)
+	assert.Equal(t, 2, calls)
+
+	s.Free()
+}
+
+func Test_SpaceAddCollisionHandler(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	ground.SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 2.0))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	began := false
+	s.AddCollisionHandler(1, 2, func(s Space, arb Arbiter, data interface{}) bool {
+		assert.Equal(t, "data", data)
+		began = true
+		return true
+	}, nil, nil, nil, "data")
+
+	for i := 0; i < 60 && !began; i++ {
+		s.Step(1.0 / 60.0)
+	}
+
+	assert.Tf(t, began, "begin func wasn't called")
+
+	s.RemoveCollisionHandler(1, 2)
+	s.RemoveShape(ball)
+	s.RemoveShape(ground)
+	s.RemoveBody(b)
+	ball.Free()
+	ground.Free()
+	b.Free()
+	s.Free()
+}
+
+func Test_SpaceStepParallel(t *testing.T) {
+	const spaces = 8
+	var wg sync.WaitGroup
+
+	steps := make([]int, spaces)
+
+	for i := 0; i < spaces; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			s := SpaceNew()
+			s.SetGravity(VectNew(0.0, -100.0))
+			s.SetUserData(i)
+
+			ground := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+			ground.SetCollisionType(1)
+
+			b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+			b.SetPosition(VectNew(0.0, 2.0))
+			b.SetUserData(i)
+			b.SetVelocityFunc(func(b Body, gravity Vect, damping, dt float64) {
+				b.UpdateVelocity(gravity, damping, dt)
+			})
+
+			ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+			ball.SetCollisionType(2)
+
+			s.AddCollisionHandler(1, 2, nil, nil, func(s Space, arb Arbiter, data interface{}) {
+				s.AddPostStepCallback(func(s Space, key interface{}) {}, arb)
+			}, nil, nil)
+
+			for j := 0; j < 120; j++ {
+				s.Step(1.0 / 60.0)
+				steps[i]++
+			}
+
+			assert.Equal(t, i, s.UserData())
+			assert.Equal(t, i, b.UserData())
+
+			s.RemoveShape(ball)
+			s.RemoveShape(ground)
+			s.RemoveBody(b)
+			ball.Free()
+			ground.Free()
+			b.Free()
+			s.Free()
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := range steps {
+		assert.Equal(t, 120, steps[i])
+	}
+}
+
+func Test_SpaceDeferredAddRemove(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	ground.SetCollisionType(1)
+
+	ballBody := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	ballBody.SetPosition(VectNew(0.0, 0.9))
+	ball := s.AddShape(CircleShapeNew(ballBody, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	b := BodyNew(1.0, 1.0)
+	removed := false
+
+	s.SetCollisionHandler(1, 2, CollisionHandlerFuncs{
+		BeginFunc: func(s Space, arb Arbiter) bool {
+			assert.T(t, s.IsLocked())
+
+			// added and removed in order, so the body is in the space in the end
+			s.RemoveBody(b)
+			s.AddBody(b)
+			s.RemoveShape(ball)
+			s.Flush()
+
+			assert.T(t, s.Contains(ball.(SpaceObject)))
+			assert.T(t, !s.Contains(b))
+			removed = true
+			return false
+		},
+	})
+
+	s.AddBody(b)
+	s.Step(1.0 / 60.0)
+
+	assert.T(t, removed)
+	assert.T(t, !s.Contains(ball.(SpaceObject)))
+	assert.T(t, s.Contains(ground.(SpaceObject)))
+	assert.T(t, s.Contains(b))
+
+	s.EachBody(func(b Body) {
+		s.RemoveBody(b)
+	})
+	s.Flush()
+	assert.T(t, !s.Contains(b))
+	assert.T(t, !s.Contains(ball.Body()))
+
+	s.Free()
+}
+
+func Test_SpaceFreeDeferredRemove(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	ground.SetCollisionType(1)
+
+	ballBody := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	ballBody.SetPosition(VectNew(0.0, 0.9))
+	ball := s.AddShape(CircleShapeNew(ballBody, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	freed := false
+	s.SetCollisionHandler(1, 2, CollisionHandlerFuncs{
+		BeginFunc: func(s Space, arb Arbiter) bool {
+			// freed once removed, after the handler returns
+			b := ball.Body()
+			s.RemoveShape(ball)
+			s.RemoveBody(b)
+			ball.Free()
+			b.Free()
+			freed = true
+			return false
+		},
+	})
+	s.Step(1.0 / 60.0)
+	assert.T(t, freed)
+
+	shapes := []Shape{}
+	for sh := range s.Shapes() {
+		shapes = append(shapes, sh)
+	}
+	assert.Equal(t, []Shape{ground}, shapes)
+
+	n := 0
+	for range s.Bodies() {
+		n++
+	}
+	assert.Equal(t, 0, n)
+
+	s.Free()
+}
+
+func Test_SpaceIterators(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+	ground.SetCollisionType(1)
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 0.9))
+	ball := s.AddShape(CircleShapeNew(b, 1.0, Origin()))
+	ball.SetCollisionType(2)
+
+	n := 0
+	for range s.Bodies() {
+		n++
+		break
+	}
+	assert.Equal(t, 1, n)
+
+	for sh := range s.Shapes() {
+		if sh == ball {
+			s.RemoveShape(sh)
+		}
+	}
+	shapes := []Shape{}
+	for sh := range s.Shapes() {
+		shapes = append(shapes, sh)
+	}
+	assert.Equal(t, []Shape{ground}, shapes)
+
+	s.AddConstraint(PivotJointNew(s.StaticBody(), ball.Body(), Origin()))
+	n = 0
+	for range s.Constraints() {
+		n++
+	}
+	assert.Equal(t, 1, n)
+
+	s.Free()
+}
+
+func Test_SpaceSegmentQueryFirst(t *testing.T) {
+	s := SpaceNew()
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 5.0))
+	ball := CircleShapeNew(b, 1.0, Origin())
+	s.AddShape(ball)
+
+	info := s.SegmentQueryFirst(VectNew(0, 10), VectNew(0, -10), AllLayers, NoGroup)
+	assert.Equal(t, Shape(ball), info.Shape)
+	assert.T(t, math.Abs(info.T-0.2) < 1e-9)
+	assert.Equal(t, VectNew(0, 1), info.N)
+
+	info = s.SegmentQueryFirst(VectNew(20, 10), VectNew(20, -10), AllLayers, NoGroup)
+	assert.Equal(t, nil, info.Shape)
+
+	s.Free()
+}
+
+func Test_SpaceNearestPointQueryNearest(t *testing.T) {
+	s := SpaceNew()
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+
+	b := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	b.SetPosition(VectNew(0.0, 5.0))
+	ball := CircleShapeNew(b, 1.0, Origin())
+	s.AddShape(ball)
+
+	info := s.NearestPointQueryNearest(VectNew(0, 8), 5.0, AllLayers, NoGroup)
+	assert.Equal(t, Shape(ball), info.Shape)
+	assert.Equal(t, VectNew(0, 6), info.P)
+	assert.Equal(t, 2.0, info.D)
+
+	info = s.NearestPointQueryNearest(VectNew(0, 20), 5.0, AllLayers, NoGroup)
+	assert.Equal(t, nil, info.Shape)
+
+	s.Free()
+}
+
+func Test_SpaceShapeQuery(t *testing.T) {
+	s := SpaceNew()
+	s.AddShape(SegmentShapeNew(s.StaticBody(), VectNew(-10, 0), VectNew(10, 0), 0.0))
+
+	ballBody := s.AddBody(BodyNew(1.0, MomentForCircle(1.0, 0.0, 1.0, Origin())))
+	ballBody.SetPosition(VectNew(0.0, 5.0))
+	ball := CircleShapeNew(ballBody, 1.0, Origin())
+	s.AddShape(ball)
+
+	b := BodyNew(1.0, 1.0)
+	b.SetPosition(VectNew(0.0, 3.5))
+	box := BoxShapeNew(b, 2.0, 2.0)
+
+	var hits []Shape
+	found := s.ShapeQuery(box, func(sh Shape, points []ContactPoint) {
+		hits = append(hits, sh)
+		assert.NotEqual(t, 0, len(points))
+	})
+	assert.T(t, found)
+	assert.Equal(t, []Shape{ball}, hits)
+
+	b.SetPosition(VectNew(0.0, 8.0))
+	assert.T(t, !s.ShapeQuery(box, func(Shape, []ContactPoint) {
+		t.Error("unexpected shape")
+	}))
+
+	box.Free()
+	b.Free()
+	s.Free()
+}
+
+func Test_SpaceTime(t *testing.T) {
+	s := SpaceNew()
+	defer s.Free()
+
+	assert.Equal(t, 0.0, s.Time())
+	s.Step(0.25)
+	s.Step(0.5)
+	assert.Equal(t, 0.75, s.Time())
+}
@@ -0,0 +1,186 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Stepper advances a space with a fixed time step driven by variable (wall-clock)
+// frame times. The time left over after the last whole step is kept for the next
+// update and exposed as an interpolation factor for rendering.
+//
+// A typical game loop:
+//
+//	st := StepperNew(space, 1.0/60.0)
+//	for {
+//		st.Update(elapsed)
+//		pos, angle := st.Transform(body) // draw the body there
+//	}
+//
+// A Stepper is not safe for concurrent use.
+type Stepper struct {
+	space       Space
+	dt          float64
+	substeps    int
+	maxSteps    int
+	accumulator float64
+	prev        map[Body]bodyTransform
+}
+
+// bodyTransform is a position and an angle of a body.
+type bodyTransform struct {
+	pos   Vect
+	angle float64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// StepperNew creates a stepper which steps a space by dt.
+// By default each step is done as a single substep and an update runs at most
+// 5 steps.
+func StepperNew(s Space, dt float64) *Stepper {
+	if !(dt > 0.0) {
+		panic("non-positive time step in StepperNew()")
+	}
+
+	return &Stepper{
+		space:    s,
+		dt:       dt,
+		substeps: 1,
+		maxSteps: 5,
+		prev:     make(map[Body]bodyTransform),
+	}
+}
+
+// Alpha returns how far the simulation time left over by the last update is
+// between the previous and the current step, in [0, 1).
+func (st *Stepper) Alpha() float64 {
+	return st.accumulator / st.dt
+}
+
+// Dt returns the fixed time step.
+func (st *Stepper) Dt() float64 {
+	return st.dt
+}
+
+// MaxSteps returns the maximum number of steps run by a single update.
+func (st *Stepper) MaxSteps() int {
+	return st.maxSteps
+}
+
+// SetMaxSteps sets the maximum number of steps run by a single update.
+// Time beyond it is dropped, so a slow simulation slows down rather than
+// taking ever longer frames to catch up ("spiral of death").
+func (st *Stepper) SetMaxSteps(n int) {
+	if n < 1 {
+		panic("invalid number of steps in SetMaxSteps()")
+	}
+	st.maxSteps = n
+}
+
+// Reset drops the accumulated time and the previous transforms, e.g. after
+// the simulation was paused or bodies were moved by hand.
+func (st *Stepper) Reset() {
+	st.accumulator = 0.0
+	st.prev = make(map[Body]bodyTransform)
+}
+
+// Space returns the space advanced by the stepper.
+func (st *Stepper) Space() Space {
+	return st.space
+}
+
+// Substeps returns the number of space steps a single step is split into.
+func (st *Stepper) Substeps() int {
+	return st.substeps
+}
+
+// SetSubsteps splits each step into n space steps of dt/n.
+func (st *Stepper) SetSubsteps(n int) {
+	if n < 1 {
+		panic("invalid number of substeps in SetSubsteps()")
+	}
+	st.substeps = n
+}
+
+// Transform returns the position and angle of a body interpolated between the
+// previous and the current step by Alpha. Bodies which weren't in the space
+// before the last step are returned as they are.
+func (st *Stepper) Transform(b Body) (Vect, float64) {
+	pos, angle := b.Position(), b.Angle()
+
+	if p, ok := st.prev[b]; ok {
+		alpha := st.Alpha()
+		pos = p.pos.Mul(1.0 - alpha).Add(pos.Mul(alpha))
+		angle = p.angle + (angle-p.angle)*alpha
+	}
+
+	return pos, angle
+}
+
+// Update adds elapsed time to the stepper and runs as many whole steps as fit,
+// but at most MaxSteps. It returns the number of steps run.
+func (st *Stepper) Update(elapsed float64) int {
+	if elapsed > 0.0 {
+		st.accumulator += elapsed
+	}
+
+	n := int(math.Floor(st.accumulator / st.dt))
+	if n > st.maxSteps {
+		n = st.maxSteps
+		st.accumulator = float64(n) * st.dt
+	}
+
+	for i := 0; i < n; i++ {
+		if i == n-1 {
+			st.save()
+		}
+
+		for j := 0; j < st.substeps; j++ {
+			st.space.Step(st.dt / float64(st.substeps))
+		}
+
+		st.accumulator -= st.dt
+	}
+
+	if st.accumulator < 0.0 {
+		st.accumulator = 0.0
+	}
+
+	return n
+}
+
+// save records the transforms of the bodies in the space before a step.
+func (st *Stepper) save() {
+	prev := make(map[Body]bodyTransform, len(st.prev))
+
+	st.space.EachBody(func(b Body) {
+		prev[b] = bodyTransform{b.Position(), b.Angle()}
+	})
+
+	st.prev = prev
+}
@@ -0,0 +1,102 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_StepperUpdate(t *testing.T) {
+	s := SpaceNew()
+	st := StepperNew(s, 0.25)
+
+	assert.Equal(t, 0, st.Update(0.125))
+	assert.Equal(t, 0.5, st.Alpha())
+
+	assert.Equal(t, 2, st.Update(0.5))
+	assert.Equal(t, 0.5, st.Alpha())
+
+	assert.Equal(t, 0, st.Update(-1.0))
+	assert.Equal(t, 0.5, st.Alpha())
+
+	st.Reset()
+	assert.Equal(t, 0.0, st.Alpha())
+
+	s.Free()
+}
+
+func Test_StepperMaxSteps(t *testing.T) {
+	s := SpaceNew()
+	st := StepperNew(s, 0.25)
+	st.SetMaxSteps(3)
+
+	assert.Equal(t, 3, st.Update(10.0))
+	assert.Equal(t, 0.0, st.Alpha())
+	assert.Equal(t, 1, st.Update(0.25))
+
+	s.Free()
+}
+
+func Test_StepperSubsteps(t *testing.T) {
+	s := SpaceNew()
+	b := s.AddBody(BodyNew(1.0, 1.0))
+
+	var dts []float64
+	b.SetVelocityFunc(func(b Body, gravity Vect, damping, dt float64) {
+		dts = append(dts, dt)
+	})
+
+	st := StepperNew(s, 0.25)
+	st.SetSubsteps(2)
+	st.Update(0.5)
+
+	assert.Equal(t, []float64{0.125, 0.125, 0.125, 0.125}, dts)
+
+	b.Free()
+	s.Free()
+}
+
+func Test_StepperTransform(t *testing.T) {
+	s := SpaceNew()
+	b := s.AddBody(BodyNew(1.0, 1.0))
+	b.SetVelocity(VectNew(4.0, 0.0))
+	b.SetAngularVelocity(2.0)
+
+	st := StepperNew(s, 0.25)
+
+	pos, angle := st.Transform(b)
+	assert.Equal(t, Origin(), pos)
+	assert.Equal(t, 0.0, angle)
+
+	assert.Equal(t, 1, st.Update(0.375))
+	assert.Equal(t, VectNew(1.0, 0.0), b.Position())
+
+	pos, angle = st.Transform(b)
+	assert.Equal(t, VectNew(0.5, 0.0), pos)
+	assert.Equal(t, 0.25, angle)
+
+	b.Free()
+	s.Free()
+}
@@ -0,0 +1,212 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"iter"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Trigger is a sensor shape tracking the bodies inside it. A body is inside while
+// any of its shapes touches the sensor, so a body with several shapes enters and
+// exits once. A body removed from the space, along with its shapes, exits.
+//
+// The callbacks are called while the space is locked, or while it's removing
+// shapes, so space objects added or removed from them are deferred.
+type Trigger struct {
+	space   Space
+	shape   Shape
+	shapes  map[Shape]Body
+	bodies  map[Body]*triggerOccupant
+	onEnter func(t *Trigger, b Body)
+	onStay  func(t *Trigger, b Body)
+	onExit  func(t *Trigger, b Body)
+}
+
+// triggerFilter is the collision filter of a trigger.
+type triggerFilter Trigger
+
+// triggerOccupant is a body inside a trigger.
+type triggerOccupant struct {
+	shapes int
+	stayed float64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Bodies returns an iterator over the bodies inside the trigger.
+// The bodies are collected before the first one is yielded, so the loop may
+// break early and mutate the trigger.
+func (t *Trigger) Bodies() iter.Seq[Body] {
+	return func(yield func(Body) bool) {
+		bodies := make([]Body, 0, len(t.bodies))
+		for b := range t.bodies {
+			bodies = append(bodies, b)
+		}
+
+		for _, b := range bodies {
+			if !yield(b) {
+				return
+			}
+		}
+	}
+}
+
+// Contains returns true if a body is inside the trigger.
+func (t *Trigger) Contains(b Body) bool {
+	_, ok := t.bodies[b]
+	return ok
+}
+
+// Free stops tracking bodies. The shape is left in the space.
+func (t *Trigger) Free() {
+	t.space.RemoveCollisionFilter(t.shape.CollisionType(), AnyCollisionType, (*triggerFilter)(t))
+	t.shapes, t.bodies = nil, nil
+}
+
+// Len returns the number of bodies inside the trigger.
+func (t *Trigger) Len() int {
+	return len(t.bodies)
+}
+
+// SetOnEnterFunc sets a function called when a body enters the trigger.
+func (t *Trigger) SetOnEnterFunc(f func(t *Trigger, b Body)) {
+	t.onEnter = f
+}
+
+// SetOnExitFunc sets a function called when a body exits the trigger.
+func (t *Trigger) SetOnExitFunc(f func(t *Trigger, b Body)) {
+	t.onExit = f
+}
+
+// SetOnStayFunc sets a function called once each step a body stays inside the
+// trigger, starting with the step after it entered.
+func (t *Trigger) SetOnStayFunc(f func(t *Trigger, b Body)) {
+	t.onStay = f
+}
+
+// Shape returns the sensor shape of the trigger.
+func (t *Trigger) Shape() Shape {
+	return t.shape
+}
+
+// TriggerNew makes a shape in a space a trigger. The shape becomes a sensor,
+// it needs a collision type of its own, which may be shared with other triggers.
+func TriggerNew(s Space, sh Shape) *Trigger {
+	t := &Trigger{
+		space:  s,
+		shape:  sh,
+		shapes: make(map[Shape]Body),
+		bodies: make(map[Body]*triggerOccupant),
+	}
+
+	sh.SetSensor(true)
+	s.AddCollisionFilter(sh.CollisionType(), AnyCollisionType, (*triggerFilter)(t))
+	return t
+}
+
+// Begin counts a shape entering the trigger.
+func (f *triggerFilter) Begin(s Space, arb Arbiter) bool {
+	t := (*Trigger)(f)
+	other, ok := t.other(arb)
+	if !ok {
+		return true
+	}
+
+	b := other.Body()
+	t.shapes[other] = b
+
+	o, inside := t.bodies[b]
+	if !inside {
+		o = &triggerOccupant{stayed: s.Time()}
+		t.bodies[b] = o
+	}
+	o.shapes++
+
+	if !inside && nil != t.onEnter {
+		t.onEnter(t, b)
+	}
+	return true
+}
+
+// PreSolve reports bodies staying in the trigger.
+func (f *triggerFilter) PreSolve(s Space, arb Arbiter) bool {
+	t := (*Trigger)(f)
+	other, ok := t.other(arb)
+	if !ok {
+		return true
+	}
+
+	o, inside := t.bodies[t.shapes[other]]
+	if !inside || o.stayed == s.Time() {
+		return true
+	}
+
+	o.stayed = s.Time()
+	if nil != t.onStay {
+		t.onStay(t, t.shapes[other])
+	}
+	return true
+}
+
+// Separate counts a shape exiting the trigger.
+func (f *triggerFilter) Separate(s Space, arb Arbiter) {
+	t := (*Trigger)(f)
+	other, ok := t.other(arb)
+	if !ok {
+		return
+	}
+
+	b, ok := t.shapes[other]
+	if !ok {
+		return
+	}
+	delete(t.shapes, other)
+
+	o := t.bodies[b]
+	if o.shapes--; o.shapes > 0 {
+		return
+	}
+
+	delete(t.bodies, b)
+	if nil != t.onExit {
+		t.onExit(t, b)
+	}
+}
+
+// other returns the shape touching the trigger shape in an arbiter, if the
+// arbiter is about the trigger at all.
+func (t *Trigger) other(arb Arbiter) (Shape, bool) {
+	a, b := arb.Shapes()
+	switch {
+	case nil == t.shapes:
+		return nil, false
+	case a == t.shape:
+		return b, true
+	case b == t.shape:
+		return a, true
+	}
+	return nil, false
+}
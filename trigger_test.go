@@ -0,0 +1,132 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+func Test_TriggerEnterStayExit(t *testing.T) {
+	s := SpaceNew()
+
+	sensor := s.AddShape(BoxShapeNew2(s.StaticBody(), BBNew(-2.0, -2.0, 2.0, 2.0)))
+	sensor.SetCollisionType(5)
+	tr := TriggerNew(s, sensor)
+
+	b := s.AddBody(BodyNew(1.0, 1.0))
+	b.SetPosition(VectNew(-5.0, 0.0))
+	for _, offset := range []Vect{VectNew(-0.5, 0.0), VectNew(0.5, 0.0)} {
+		sh := s.AddShape(CircleShapeNew(b, 0.5, offset))
+		sh.SetCollisionType(2)
+	}
+
+	var events []string
+	stays := 0
+	tr.SetOnEnterFunc(func(tr *Trigger, body Body) {
+		assert.Equal(t, b, body)
+		events = append(events, "enter")
+	})
+	tr.SetOnStayFunc(func(tr *Trigger, body Body) {
+		stays++
+	})
+	tr.SetOnExitFunc(func(tr *Trigger, body Body) {
+		assert.Equal(t, b, body)
+		events = append(events, "exit")
+	})
+
+	// a handler for the trigger type still gets the collisions
+	begins := 0
+	s.SetCollisionHandler(5, 2, CollisionHandlerFuncs{
+		BeginFunc: func(s Space, arb Arbiter) bool {
+			begins++
+			return true
+		},
+	})
+
+	b.SetVelocity(VectNew(6.0, 0.0))
+	for i := 0; i < 60; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Equal(t, []string{"enter"}, events)
+	assert.T(t, tr.Contains(b))
+	assert.Equal(t, 1, tr.Len())
+	assert.T(t, stays > 0 && stays < 60)
+	assert.Equal(t, 2, begins)
+
+	for i := 0; i < 60; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Equal(t, []string{"enter", "exit"}, events)
+	assert.T(t, !tr.Contains(b))
+	assert.Equal(t, 0, tr.Len())
+
+	tr.Free()
+	s.Free()
+}
+
+func Test_TriggerBodyRemoved(t *testing.T) {
+	s := SpaceNew()
+
+	sensor := s.AddShape(BoxShapeNew2(s.StaticBody(), BBNew(-2.0, -2.0, 2.0, 2.0)))
+	sensor.SetCollisionType(5)
+	tr := TriggerNew(s, sensor)
+
+	b := s.AddBody(BodyNew(1.0, 1.0))
+	b.SetPosition(VectNew(-5.0, 0.0))
+	for _, offset := range []Vect{VectNew(-0.5, 0.0), VectNew(0.5, 0.0)} {
+		sh := s.AddShape(CircleShapeNew(b, 0.5, offset))
+		sh.SetCollisionType(2)
+	}
+
+	exits := 0
+	tr.SetOnExitFunc(func(tr *Trigger, body Body) {
+		exits++
+	})
+
+	// remove the body from within a callback, while the space is locked
+	tr.SetOnStayFunc(func(tr *Trigger, body Body) {
+		for sh := range body.Shapes() {
+			s.RemoveShape(sh)
+		}
+		s.RemoveBody(body)
+	})
+
+	b.SetPosition(Origin())
+	s.Step(1.0 / 60.0)
+	assert.T(t, tr.Contains(b))
+
+	s.Step(1.0 / 60.0)
+	assert.Equal(t, 1, exits)
+	assert.T(t, !tr.Contains(b))
+
+	var bodies []Body
+	for body := range tr.Bodies() {
+		bodies = append(bodies, body)
+	}
+	assert.Equal(t, 0, len(bodies))
+
+	tr.Free()
+	s.Free()
+}
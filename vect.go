@@ -45,6 +45,11 @@ func (a Vect) Add(b Vect) Vect {
 	return VectNew(a.X+b.X, a.Y+b.Y)
 }
 
+// Cross returns the z component of the cross product of two vectors.
+func (v1 Vect) Cross(v2 Vect) float64 {
+	return v1.X*v2.Y - v1.Y*v2.X
+}
+
 // Dist returns distance between two vectors.
 func (a Vect) Dist(b Vect) float64 {
 	return a.Sub(b).Length()
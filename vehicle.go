@@ -0,0 +1,262 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"math"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Vehicle is a chassis body with wheel bodies. Every wheel slides along a vertical
+// groove of the chassis, is held by a damped spring (the suspension) and is driven
+// or braked by a simple motor.
+//
+// The controls may be changed at any time, e.g. from a collision handler.
+type Vehicle struct {
+	space    Space
+	chassis  Body
+	shape    Shape
+	opts     VehicleOptions
+	throttle float64
+	brake    float64
+	wheels   []vehicleWheel
+}
+
+// VehicleOptions configure a vehicle.
+type VehicleOptions struct {
+	// Width, Height and Mass of the box shaped chassis.
+	Width  float64
+	Height float64
+	Mass   float64
+	// Group is the collision group of the chassis and wheel shapes, it should be
+	// distinct for every vehicle.
+	Group Group
+	// Wheels of the vehicle.
+	Wheels []WheelOptions
+}
+
+// WheelTelemetry is the state of a wheel after a step.
+type WheelTelemetry struct {
+	// Speed is the angular velocity of the wheel relative to the chassis.
+	Speed float64
+	// Compression is how much the suspension spring is shorter than its rest length.
+	Compression float64
+	// Grounded is true if the wheel touches anything.
+	Grounded bool
+	// Load is the force pressing the wheel against what it touches.
+	Load float64
+	// Traction is the friction force the wheel applies on what it touches.
+	Traction float64
+}
+
+// WheelOptions configure a wheel of a vehicle.
+type WheelOptions struct {
+	// Offset is the position of the wheel relative to the chassis when the
+	// suspension is at rest.
+	Offset Vect
+	// Radius, Mass and Friction of the wheel.
+	Radius   float64
+	Mass     float64
+	Friction float64
+	// Travel is how far the wheel slides up and down from its rest position.
+	Travel float64
+	// Stiffness and Damping of the suspension spring.
+	Stiffness float64
+	Damping   float64
+	// Driven wheels are turned by the throttle.
+	Driven bool
+	// MaxSpeed is the angular speed of a driven wheel at full throttle, and
+	// MotorTorque is the maximum torque the motor uses to get there.
+	MaxSpeed    float64
+	MotorTorque float64
+	// BrakeTorque is the maximum torque used to stop the wheel at full brake.
+	BrakeTorque float64
+}
+
+// vehicleWheel is a wheel of a vehicle with its constraints.
+type vehicleWheel struct {
+	body   Body
+	shape  CircleShape
+	groove GrooveJoint
+	spring DampedSpring
+	motor  SimpleMotor
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Brake returns the brake, from 0 to 1.
+func (v *Vehicle) Brake() float64 {
+	return v.brake
+}
+
+// Chassis returns the body of the chassis.
+func (v *Vehicle) Chassis() Body {
+	return v.chassis
+}
+
+// Free removes the chassis, the wheels and their constraints from the space and
+// frees them.
+func (v *Vehicle) Free() {
+	for _, w := range v.wheels {
+		for _, c := range []Constraint{w.groove, w.spring, w.motor} {
+			v.space.RemoveConstraint(c)
+			c.Free()
+		}
+		v.space.RemoveShape(w.shape)
+		w.shape.Free()
+		v.space.RemoveBody(w.body)
+		w.body.Free()
+	}
+
+	v.space.RemoveShape(v.shape)
+	v.shape.Free()
+	v.space.RemoveBody(v.chassis)
+	v.chassis.Free()
+
+	v.wheels = nil
+}
+
+// SetBrake sets the brake, from 0 (released) to 1 (full brake). The brake acts on
+// all the wheels and overrides the throttle.
+func (v *Vehicle) SetBrake(brake float64) {
+	v.brake = math.Max(0.0, math.Min(1.0, brake))
+	v.updateMotors()
+}
+
+// SetThrottle sets the throttle, from -1 (full reverse) to 1 (full forward).
+// Forward drives the vehicle towards the positive x axis of the chassis. A zero
+// throttle lets the driven wheels roll freely.
+func (v *Vehicle) SetThrottle(throttle float64) {
+	v.throttle = math.Max(-1.0, math.Min(1.0, throttle))
+	v.updateMotors()
+}
+
+// Telemetry returns the state of a wheel. The wheel load and traction come from
+// the arbiters of the wheel, so they are only meaningful after a step.
+func (v *Vehicle) Telemetry(wheel int) WheelTelemetry {
+	w := v.wheels[wheel]
+	t := WheelTelemetry{
+		Speed: w.body.AngularVelocity() - v.chassis.AngularVelocity(),
+	}
+
+	anchr := v.chassis.LocalToWorld(w.spring.Anchr1())
+	t.Compression = w.spring.RestLength() - w.body.Position().Sub(anchr).Length()
+
+	dt := v.space.CurrentTimeStep()
+	w.body.EachArbiter(func(_ Body, arb Arbiter) {
+		if 0 == arb.Count() {
+			return
+		}
+
+		t.Grounded = true
+		if dt > 0.0 {
+			j, n := arb.TotalImpulseWithFriction(), arb.Normal(0)
+			t.Load += math.Abs(j.Dot(n)) / dt
+			t.Traction += math.Abs(j.Cross(n)) / dt
+		}
+	})
+
+	return t
+}
+
+// Throttle returns the throttle, from -1 to 1.
+func (v *Vehicle) Throttle() float64 {
+	return v.throttle
+}
+
+// VehicleNew creates a vehicle with the center of its chassis at a position and
+// adds it to a space.
+func VehicleNew(s Space, pos Vect, opts VehicleOptions) *Vehicle {
+	if 0 == len(opts.Wheels) {
+		panic("vehicle without wheels in VehicleNew()")
+	}
+
+	v := &Vehicle{space: s, opts: opts}
+
+	v.chassis = s.AddBody(BodyNew(opts.Mass, MomentForBox(opts.Mass, opts.Width, opts.Height)))
+	v.chassis.SetPosition(pos)
+	v.shape = BoxShapeNew(v.chassis, opts.Width, opts.Height)
+	v.shape.SetGroup(opts.Group)
+	s.AddShape(v.shape)
+
+	for _, wo := range opts.Wheels {
+		var w vehicleWheel
+		top, bottom := wo.Offset.Add(VectNew(0.0, wo.Travel)), wo.Offset.Sub(VectNew(0.0, wo.Travel))
+
+		w.body = s.AddBody(BodyNew(wo.Mass, MomentForCircle(wo.Mass, 0.0, wo.Radius, Origin())))
+		w.body.SetPosition(v.chassis.LocalToWorld(wo.Offset))
+
+		w.shape = CircleShapeNew(w.body, wo.Radius, Origin())
+		w.shape.SetFriction(wo.Friction)
+		w.shape.SetGroup(opts.Group)
+		s.AddShape(w.shape)
+
+		w.groove = GrooveJointNew(v.chassis, w.body, top, bottom, Origin())
+		w.spring = DampedSpringNew(v.chassis, w.body, top, Origin(), wo.Travel, wo.Stiffness, wo.Damping)
+		w.motor = SimpleMotorNew(v.chassis, w.body, 0.0)
+		for _, c := range []Constraint{w.groove, w.spring, w.motor} {
+			s.AddConstraint(c)
+		}
+
+		v.wheels = append(v.wheels, w)
+	}
+
+	v.updateMotors()
+	return v
+}
+
+// Wheel returns the body of a wheel.
+func (v *Vehicle) Wheel(wheel int) Body {
+	return v.wheels[wheel].body
+}
+
+// Wheels returns the number of wheels.
+func (v *Vehicle) Wheels() int {
+	return len(v.wheels)
+}
+
+// updateMotors sets the rate and the maximum torque of the wheel motors from the
+// throttle and the brake.
+func (v *Vehicle) updateMotors() {
+	for i, w := range v.wheels {
+		wo := v.opts.Wheels[i]
+
+		switch {
+		case v.brake > 0.0:
+			w.motor.SetRate(0.0)
+			w.motor.SetMaxForce(v.brake * wo.BrakeTorque)
+		case wo.Driven && 0.0 != v.throttle:
+			// the motor turns the wheel relative to the chassis at minus the rate,
+			// so a positive one turns it clockwise, rolling towards positive x
+			w.motor.SetRate(v.throttle * wo.MaxSpeed)
+			w.motor.SetMaxForce(math.Abs(v.throttle) * wo.MotorTorque)
+		default:
+			w.motor.SetRate(0.0)
+			w.motor.SetMaxForce(0.0)
+		}
+
+		w.body.Activate()
+	}
+}
@@ -0,0 +1,117 @@
+package chipmunk
+
+/*
+Copyright © 2012 Serge Zirukin
+
+Permission is hereby granted, free of charge, to any person obtaining
+a copy of this software and associated documentation files (the
+"Software"), to deal in the Software without restriction, including
+without limitation the rights to use, copy, modify, merge, publish,
+distribute, sublicense, and/or sell copies of the Software, and to
+permit persons to whom the Software is furnished to do so, subject to
+the following conditions:
+
+The above copyright notice and this permission notice shall be
+included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+import (
+	"github.com/bmizerany/assert"
+	"testing"
+)
+
+// vehicleOptions returns the options of a car with a driven front wheel.
+func vehicleOptions() VehicleOptions {
+	wheel := WheelOptions{
+		Radius:      1.0,
+		Mass:        1.0,
+		Friction:    1.0,
+		Travel:      1.0,
+		Stiffness:   500.0,
+		Damping:     20.0,
+		Driven:      true,
+		MaxSpeed:    10.0,
+		MotorTorque: 1000.0,
+		BrakeTorque: 1000.0,
+	}
+	front, rear := wheel, wheel
+	front.Offset, rear.Offset = VectNew(2.0, -1.5), VectNew(-2.0, -1.5)
+	rear.Driven = false
+
+	return VehicleOptions{
+		Width:  6.0,
+		Height: 1.0,
+		Mass:   5.0,
+		Group:  1,
+		Wheels: []WheelOptions{front, rear},
+	}
+}
+
+func Test_VehicleTelemetry(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := SegmentShapeNew(s.StaticBody(), VectNew(-1000.0, 0.0), VectNew(1000.0, 0.0), 0.0)
+	ground.SetFriction(1.0)
+	s.AddStaticShape(ground)
+
+	v := VehicleNew(s, VectNew(0.0, 3.0), vehicleOptions())
+
+	assert.Equal(t, 2, v.Wheels())
+
+	for i := 0; i < 180; i++ {
+		s.Step(1.0 / 60.0)
+	}
+
+	for i := 0; i < v.Wheels(); i++ {
+		tm := v.Telemetry(i)
+		assert.Tf(t, tm.Grounded, "wheel %d isn't on the ground", i)
+		assert.Tf(t, tm.Compression > 0.0, "suspension %d isn't compressed: %v", i, tm.Compression)
+		assert.Tf(t, tm.Load > 0.0, "wheel %d has no load: %v", i, tm.Load)
+	}
+
+	v.Free()
+	s.Free()
+}
+
+func Test_VehicleThrottleBrake(t *testing.T) {
+	s := SpaceNew()
+	s.SetGravity(VectNew(0.0, -100.0))
+
+	ground := SegmentShapeNew(s.StaticBody(), VectNew(-1000.0, 0.0), VectNew(1000.0, 0.0), 0.0)
+	ground.SetFriction(1.0)
+	s.AddStaticShape(ground)
+
+	v := VehicleNew(s, VectNew(0.0, 3.0), vehicleOptions())
+
+	for i := 0; i < 60; i++ {
+		s.Step(1.0 / 60.0)
+	}
+
+	v.SetThrottle(2.0)
+	assert.Equal(t, 1.0, v.Throttle())
+	for i := 0; i < 120; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, v.Chassis().Position().X > 5.0, "vehicle didn't drive: %v", v.Chassis().Position())
+	assert.Tf(t, v.Telemetry(0).Speed < 0.0, "front wheel doesn't turn clockwise")
+	assert.Tf(t, v.Telemetry(0).Traction > 0.0, "front wheel has no traction")
+
+	v.SetBrake(1.0)
+	for i := 0; i < 180; i++ {
+		s.Step(1.0 / 60.0)
+	}
+	assert.Tf(t, v.Chassis().Velocity().Length() < 0.1, "vehicle didn't stop: %v", v.Chassis().Velocity())
+	assert.Tf(t, v.Telemetry(1).Speed > -0.1, "rear wheel isn't braked")
+
+	v.Free()
+	s.Free()
+}